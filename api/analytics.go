@@ -0,0 +1,131 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"product-like/db"
+	"product-like/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultAnalyticsPeriod is how far back the like analytics go without from.
+	defaultAnalyticsPeriod = 30 * 24 * time.Hour
+	// maxAnalyticsPeriod bounds the period, and so the length of the time series.
+	maxAnalyticsPeriod = 731 * 24 * time.Hour
+)
+
+// GetShopLikeAnalytics retrieves the like activity of a shop's products: the
+// totals, every product's likes, unlikes and likers, and a time series of
+// likes and unlikes per interval (day, week or month). The period runs from
+// from until, but excluding, to, by default the last 30 days. With format=csv
+// the product stats, or the time series with report=series, are downloaded
+// as a CSV file instead.
+func GetShopLikeAnalytics(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := authorizeShopManagement(c, dbConn)
+		if !ok {
+			return
+		}
+
+		query := newQueryParser(c)
+		interval := c.DefaultQuery("interval", models.AnalyticsIntervalDay)
+		switch interval {
+		case models.AnalyticsIntervalDay, models.AnalyticsIntervalWeek, models.AnalyticsIntervalMonth:
+		default:
+			query.errors["interval"] = "must be one of: day, week, month"
+		}
+
+		to := time.Now().UTC()
+		if value := query.Time("to"); value != nil {
+			to = value.UTC()
+		}
+		from := to.Add(-defaultAnalyticsPeriod)
+		if value := query.Time("from"); value != nil {
+			from = value.UTC()
+		}
+		switch {
+		case !from.Before(to):
+			query.errors["from"] = "must be before to"
+		case to.Sub(from) > maxAnalyticsPeriod:
+			query.errors["from"] = "must be at most 731 days before to"
+		}
+
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			query.errors["format"] = "must be json or csv"
+		}
+		report := c.DefaultQuery("report", "products")
+		if report != "products" && report != "series" {
+			query.errors["report"] = "must be products or series"
+		}
+
+		if !query.Valid() {
+			return
+		}
+
+		analytics, err := db.GetShopLikeAnalytics(c.Request.Context(), dbConn, shopID, interval, from, to)
+		if err != nil {
+			log.Println("Error fetching like analytics:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch like analytics"})
+			return
+		}
+
+		if format == "csv" {
+			writeLikeAnalyticsCSV(c, analytics, report)
+			return
+		}
+
+		c.JSON(http.StatusOK, analytics)
+	}
+}
+
+// writeLikeAnalyticsCSV writes the product stats, or the time series if
+// report is "series", as a CSV attachment.
+func writeLikeAnalyticsCSV(c *gin.Context, analytics models.LikeAnalytics, report string) {
+	var records [][]string
+	if report == "series" {
+		records = append(records, []string{"start", "likes", "unlikes"})
+		for _, bucket := range analytics.Series {
+			records = append(records, []string{
+				bucket.Start.Format(time.RFC3339), formatInt(bucket.Likes), formatInt(bucket.Unlikes),
+			})
+		}
+	} else {
+		records = append(records, []string{
+			"product_id", "name", "like_count", "likes", "unlikes", "unique_likers", "likes_to_unlikes",
+		})
+		for _, stats := range analytics.Products {
+			ratio := ""
+			if stats.LikesToUnlikes != nil {
+				ratio = strconv.FormatFloat(*stats.LikesToUnlikes, 'f', 2, 64)
+			}
+			records = append(records, []string{
+				formatInt(stats.ProductID), stats.Name, formatInt(stats.LikeCount), formatInt(stats.Likes),
+				formatInt(stats.Unlikes), formatInt(stats.UniqueLikers), ratio,
+			})
+		}
+	}
+
+	filename := fmt.Sprintf("shop-%d-likes-%s-%s-%s.csv",
+		analytics.ShopID, report, analytics.From.Format("20060102"), analytics.To.Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	if err := w.WriteAll(records); err != nil {
+		log.Println("Error writing like analytics CSV:", err)
+	}
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
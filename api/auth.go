@@ -0,0 +1,192 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Register creates a new user account and returns an access and refresh token for it.
+func Register(dbConn *sql.DB, authMiddleware *auth.AuthMiddleware, refreshTokens *auth.RefreshTokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			Name     string `json:"name"`
+			Email    string `json:"email" binding:"required,email"`
+			Password string `json:"password" binding:"required,min=8"`
+			Phone    string `json:"phone"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Hash the user's password before storing it in the database
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		user := models.NewUser(request.Name, normalizeEmail(request.Email), string(hashedPassword), request.Phone, models.UserStatusActive)
+		if err := db.CreateUser(c.Request.Context(), dbConn, user); err != nil {
+			if errors.Is(err, db.ErrEmailTaken) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+				return
+			}
+			log.Println("Error creating user:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the user"})
+			return
+		}
+
+		startSession(c, http.StatusCreated, authMiddleware, refreshTokens, user)
+	}
+}
+
+// Login verifies a user's credentials and returns an access and refresh token.
+func Login(dbConn *sql.DB, authMiddleware *auth.AuthMiddleware, refreshTokens *auth.RefreshTokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			Email    string `json:"email" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := db.GetUserByEmail(c.Request.Context(), dbConn, normalizeEmail(request.Email))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Println("Error fetching user:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		// Use the same message for unknown emails and wrong passwords so the
+		// endpoint cannot be used to probe for registered accounts.
+		if user == nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)) != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			return
+		}
+
+		if !user.IsActive() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User account is not active"})
+			return
+		}
+
+		startSession(c, http.StatusOK, authMiddleware, refreshTokens, user)
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a rotated refresh token.
+func RefreshToken(dbConn *sql.DB, authMiddleware *auth.AuthMiddleware, refreshTokens *auth.RefreshTokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID, refreshToken, err := refreshTokens.Rotate(c.Request.Context(), request.RefreshToken)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrRefreshTokenReused):
+				log.Printf("Refresh token reuse detected for user %d, session revoked", userID)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used"})
+			case errors.Is(err, auth.ErrInvalidRefreshToken):
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			default:
+				log.Println("Error rotating refresh token:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			}
+			return
+		}
+
+		// The user may have been suspended since the session started
+		user, err := db.GetUserByID(c.Request.Context(), dbConn, userID)
+		if err != nil {
+			log.Println("Error fetching user:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		if !user.IsActive() {
+			if err := refreshTokens.Revoke(c.Request.Context(), refreshToken); err != nil {
+				log.Println("Error revoking refresh token:", err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User account is not active"})
+			return
+		}
+
+		respondWithTokens(c, http.StatusOK, authMiddleware, user, refreshToken)
+	}
+}
+
+// Logout revokes the session the refresh token belongs to.
+func Logout(refreshTokens *auth.RefreshTokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := refreshTokens.Revoke(c.Request.Context(), request.RefreshToken); err != nil {
+			log.Println("Error revoking refresh token:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	}
+}
+
+// startSession issues a new refresh token family for user and writes both tokens.
+func startSession(c *gin.Context, status int, authMiddleware *auth.AuthMiddleware, refreshTokens *auth.RefreshTokens, user *models.User) {
+	refreshToken, err := refreshTokens.Issue(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Println("Error issuing refresh token:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	respondWithTokens(c, status, authMiddleware, user, refreshToken)
+}
+
+// respondWithTokens writes a new access token for user along with the refresh token and the user itself.
+func respondWithTokens(c *gin.Context, status int, authMiddleware *auth.AuthMiddleware, user *models.User, refreshToken string) {
+	token, err := authMiddleware.GenerateToken(user)
+	if err != nil {
+		log.Println("Error generating token:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"token":         token,
+		"token_type":    "Bearer",
+		"expires_in":    int(auth.AccessTokenTTL.Seconds()),
+		"refresh_token": refreshToken,
+		"user":          user,
+	})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,448 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collectionRequest is the request body for creating and renaming collections.
+type collectionRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// GetCollections retrieves the authenticated user's collections, the default
+// collection holding all of their likes first.
+func GetCollections(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		collections, err := db.ListCollections(c.Request.Context(), dbConn, user.ID)
+		if err != nil {
+			log.Println("Error fetching collections:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collections"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"collections": collections})
+	}
+}
+
+// CreateCollection creates a collection for the authenticated user.
+func CreateCollection(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var request collectionRequest
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		collection := models.Collection{UserID: user.ID, Name: request.Name}
+		if err := db.CreateCollection(c.Request.Context(), dbConn, &collection); err != nil {
+			if errors.Is(err, db.ErrCollectionNameTaken) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Collection name already taken"})
+				return
+			}
+			if errors.Is(err, db.ErrCollectionNameReserved) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Collection name is reserved"})
+				return
+			}
+			log.Println("Error creating collection:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the collection"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Collection created successfully", "collection": collection})
+	}
+}
+
+// RenameCollection renames one of the authenticated user's collections.
+func RenameCollection(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		collectionID, ok := parseCollectionID(c)
+		if !ok {
+			return
+		}
+
+		var request collectionRequest
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		if err := db.RenameCollection(c.Request.Context(), dbConn, user.ID, collectionID, request.Name); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+			case errors.Is(err, db.ErrCollectionNameTaken):
+				c.JSON(http.StatusConflict, gin.H{"error": "Collection name already taken"})
+			case errors.Is(err, db.ErrCollectionNameReserved):
+				c.JSON(http.StatusConflict, gin.H{"error": "Collection name is reserved"})
+			default:
+				log.Println("Error renaming collection:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename the collection"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Collection renamed successfully"})
+	}
+}
+
+// ReorderCollections sets the order of the authenticated user's collections.
+// The request lists the IDs of the collections to order; the default
+// collection always stays first.
+func ReorderCollections(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var request struct {
+			CollectionIDs []int64 `json:"collection_ids" binding:"required,min=1,unique,dive,min=1"`
+		}
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		if err := db.ReorderCollections(c.Request.Context(), dbConn, user.ID, request.CollectionIDs); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+				return
+			}
+			log.Println("Error reordering collections:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder collections"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Collections reordered successfully"})
+	}
+}
+
+// DeleteCollection deletes one of the authenticated user's collections. The
+// likes in it are kept. The default collection cannot be deleted.
+func DeleteCollection(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collection, ok := loadCollection(c, dbConn)
+		if !ok {
+			return
+		}
+
+		if collection.IsDefault {
+			c.JSON(http.StatusConflict, gin.H{"error": "The default collection cannot be deleted"})
+			return
+		}
+
+		if _, err := db.DeleteCollection(c.Request.Context(), dbConn, collection.UserID, collection.ID); err != nil {
+			log.Println("Error deleting collection:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete the collection"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+	}
+}
+
+// AddCollectionProduct places a product the authenticated user likes into
+// one of their collections. Adding a product that already is there succeeds
+// without changes.
+func AddCollectionProduct(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collection, productID, ok := loadCollectionProduct(c, dbConn)
+		if !ok {
+			return
+		}
+
+		added, err := db.AddCollectionProduct(c.Request.Context(), dbConn, collection.UserID, collection.ID, productID)
+		if err != nil {
+			if errors.Is(err, db.ErrProductNotLiked) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Liked product not found"})
+				return
+			}
+			log.Println("Error adding product to collection:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add the product to the collection"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"collection_id": collection.ID, "product_id": productID, "changed": added})
+	}
+}
+
+// RemoveCollectionProduct takes a product out of one of the authenticated
+// user's collections without unliking it. Removing a product that is not
+// there succeeds without changes.
+func RemoveCollectionProduct(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collection, productID, ok := loadCollectionProduct(c, dbConn)
+		if !ok {
+			return
+		}
+
+		removed, err := db.RemoveCollectionProduct(c.Request.Context(), dbConn, collection.UserID, collection.ID, productID)
+		if err != nil {
+			log.Println("Error removing product from collection:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the product from the collection"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"collection_id": collection.ID, "product_id": productID, "changed": removed})
+	}
+}
+
+// GetCollectionProducts retrieves a page of the products in one of the
+// authenticated user's collections, most recently added first. The default
+// collection lists all liked products, most recently liked first, like
+// /api/liked-products.
+func GetCollectionProducts(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		collection, ok := loadCollection(c, dbConn)
+		if !ok {
+			return
+		}
+
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		var (
+			products   []models.LikedProduct
+			totalCount int
+			err        error
+		)
+		if collection.IsDefault {
+			products, totalCount, err = db.RetrieveLikedProducts(c.Request.Context(), dbConn, int(collection.UserID), false, page, limit)
+		} else {
+			products, totalCount, err = db.ListCollectionProducts(c.Request.Context(), dbConn, collection.ID, page, limit)
+		}
+		if err != nil {
+			log.Println("Error fetching collection products:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"collection":     collection,
+			"liked_products": products,
+			"total_count":    totalCount,
+			"page":           page,
+			"limit":          limit,
+		})
+	}
+}
+
+// ShareCollection makes one of the authenticated user's collections public
+// and returns its share slug. Sharing an already shared collection keeps its link.
+func ShareCollection(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shareCollection(c, dbConn, false)
+	}
+}
+
+// RegenerateCollectionShare gives one of the authenticated user's collections
+// a new share slug, so that the old link stops working.
+func RegenerateCollectionShare(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shareCollection(c, dbConn, true)
+	}
+}
+
+// shareCollection shares a collection, with a new slug if regenerate is set,
+// and writes the resulting slug.
+func shareCollection(c *gin.Context, dbConn *sql.DB, regenerate bool) {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	collectionID, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	slug, err := randomToken(16)
+	if err != nil {
+		log.Println("Error generating share slug:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share the collection"})
+		return
+	}
+
+	slug, err = db.ShareCollection(c.Request.Context(), dbConn, user.ID, collectionID, slug, regenerate)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+			return
+		}
+		log.Println("Error sharing collection:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share the collection"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collection_id": collectionID, "share_slug": slug})
+}
+
+// UnshareCollection revokes the public link of one of the authenticated user's collections.
+func UnshareCollection(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		collectionID, ok := parseCollectionID(c)
+		if !ok {
+			return
+		}
+
+		if err := db.UnshareCollection(c.Request.Context(), dbConn, user.ID, collectionID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+				return
+			}
+			log.Println("Error unsharing collection:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unshare the collection"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Collection link revoked successfully"})
+	}
+}
+
+// GetPublicWishlist retrieves a page of a shared collection by its share slug
+// without authentication. It shows the products only, nothing about the user
+// who shared them; deleted products are left out and products that can no
+// longer be bought are flagged as unavailable.
+func GetPublicWishlist(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		collection, err := db.GetSharedCollection(c.Request.Context(), dbConn, c.Param("slug"))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+				return
+			}
+			log.Println("Error fetching shared collection:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the wishlist"})
+			return
+		}
+
+		products, totalCount, err := db.ListSharedCollectionProducts(c.Request.Context(), dbConn, collection, page, limit)
+		if err != nil {
+			log.Println("Error fetching shared collection products:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the wishlist"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"wishlist": gin.H{
+				"name":      collection.Name,
+				"shared_at": collection.SharedAt,
+			},
+			"products":    products,
+			"total_count": totalCount,
+			"page":        page,
+			"limit":       limit,
+		})
+	}
+}
+
+// randomToken returns n random bytes encoded as an unguessable URL-safe string.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// loadCollection loads the authenticated user's collection named in the URL.
+// Otherwise it writes the error response and returns false.
+func loadCollection(c *gin.Context, dbConn *sql.DB) (*models.Collection, bool) {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	collectionID, ok := parseCollectionID(c)
+	if !ok {
+		return nil, false
+	}
+
+	collection, err := db.GetCollection(c.Request.Context(), dbConn, user.ID, collectionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+			return nil, false
+		}
+		log.Println("Error fetching collection:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the collection"})
+		return nil, false
+	}
+	return collection, true
+}
+
+// loadCollectionProduct loads the collection and reads the product ID in the
+// URL for adding or removing a product. The default collection always holds
+// every liked product, so products cannot be placed into or taken out of it.
+func loadCollectionProduct(c *gin.Context, dbConn *sql.DB) (*models.Collection, int64, bool) {
+	collection, ok := loadCollection(c, dbConn)
+	if !ok {
+		return nil, 0, false
+	}
+
+	if collection.IsDefault {
+		c.JSON(http.StatusConflict, gin.H{"error": "The default collection holds all liked products"})
+		return nil, 0, false
+	}
+
+	productID, err := strconv.ParseInt(c.Param("product_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return nil, 0, false
+	}
+	return collection, productID, true
+}
+
+// parseCollectionID reads the collection ID from the URL parameters. On
+// invalid input it writes the error response and returns false.
+func parseCollectionID(c *gin.Context) (int64, bool) {
+	collectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return 0, false
+	}
+	return collectionID, true
+}
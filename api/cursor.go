@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"product-like/db"
+	"product-like/pkg/cursor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keysetCursor is the payload of the next_cursor and prev_cursor values.
+type keysetCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// usesOffsetPagination reports whether the client asked for the page/limit
+// compatibility mode rather than cursor pagination.
+func usesOffsetPagination(c *gin.Context) bool {
+	_, hasPage := c.GetQuery("page")
+	_, hasCursor := c.GetQuery("cursor")
+	return hasPage && !hasCursor
+}
+
+// parseKeysetPage reads the cursor and limit query parameters for the list
+// identified by scope. On invalid input it writes the error response and returns false.
+func parseKeysetPage(c *gin.Context, cursors *cursor.Codec, scope string) (db.KeysetPage, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return db.KeysetPage{}, false
+	}
+
+	page := db.KeysetPage{Limit: limit}
+	if value := c.Query("cursor"); value != "" {
+		var position keysetCursor
+		if err := cursors.Decode(scope, value, &position); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+			return db.KeysetPage{}, false
+		}
+		page.Cursor = &db.Keyset{CreatedAt: position.CreatedAt, ID: position.ID}
+		page.Backward = position.Backward
+	}
+
+	return page, true
+}
+
+// keysetCursors returns the next_cursor and prev_cursor values for a fetched
+// page; either is nil when there is nothing more in that direction.
+func keysetCursors(cursors *cursor.Codec, scope string, result db.KeysetResult) (interface{}, interface{}, error) {
+	var next, prev interface{}
+	if result.HasNext {
+		encoded, err := cursors.Encode(scope, keysetCursor{CreatedAt: result.Last.CreatedAt, ID: result.Last.ID})
+		if err != nil {
+			return nil, nil, err
+		}
+		next = encoded
+	}
+	if result.HasPrev {
+		encoded, err := cursors.Encode(scope, keysetCursor{CreatedAt: result.First.CreatedAt, ID: result.First.ID, Backward: true})
+		if err != nil {
+			return nil, nil, err
+		}
+		prev = encoded
+	}
+	return next, prev, nil
+}
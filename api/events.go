@@ -0,0 +1,68 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"product-like/db"
+	"product-like/pkg/live"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventsHeartbeat is how often an idle event stream sends a ping, so proxies
+// keep the connection open and clients notice when it drops.
+const eventsHeartbeat = 15 * time.Second
+
+// ProductEvents streams changes of a product as Server-Sent Events. The
+// stream starts with a product event carrying the current state, followed by
+// like_count, product and deleted events as they happen.
+func ProductEvents(dbConn *sql.DB, updates *live.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, ok := parseProductID(c)
+		if !ok {
+			return
+		}
+
+		// Subscribe before reading the product, so no change falls in between
+		ch, unsubscribe := updates.Subscribe(productID)
+		defer unsubscribe()
+
+		product, err := db.GetProduct(c.Request.Context(), dbConn, productID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+				return
+			}
+			log.Println("Error fetching product:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the product"})
+			return
+		}
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.SSEvent(live.UpdateProduct, live.ProductUpdate(product))
+		// gin only flushes after a Stream step, send the snapshot right away
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(eventsHeartbeat)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case update := <-ch:
+				c.SSEvent(update.Type, update)
+				return update.Type != live.UpdateDeleted
+			case <-heartbeat.C:
+				c.SSEvent("ping", time.Now().Unix())
+				return true
+			}
+		})
+	}
+}
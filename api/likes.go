@@ -0,0 +1,250 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/auth"
+	"product-like/pkg/live"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PutProductLike likes the product in the URL for the authenticated user. It
+// is idempotent: liking an already liked product succeeds without changes.
+func PutProductLike(dbConn *sql.DB, updates *live.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setProductLike(c, dbConn, updates, true)
+	}
+}
+
+// DeleteProductLike removes the authenticated user's like of the product in
+// the URL. It is idempotent: unliking a product that is not liked succeeds
+// without changes.
+func DeleteProductLike(dbConn *sql.DB, updates *live.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setProductLike(c, dbConn, updates, false)
+	}
+}
+
+// GetLikeStatuses reports which of up to 100 requested products the
+// authenticated user likes, as a map of product ID to liked.
+func GetLikeStatuses(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var request struct {
+			ProductIDs []int64 `json:"product_ids" binding:"required,min=1,max=100,dive,min=1"`
+		}
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		statuses, err := db.GetLikeStatuses(c.Request.Context(), dbConn, user.ID, request.ProductIDs)
+		if err != nil {
+			log.Println("Error fetching like statuses:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch like statuses"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"liked": statuses})
+	}
+}
+
+// likeBatchRequest is the request body of a like batch.
+type likeBatchRequest struct {
+	Operations []struct {
+		ProductID int64  `json:"product_id" binding:"required,min=1"`
+		Action    string `json:"action" binding:"required,oneof=like unlike"`
+	} `json:"operations" binding:"required,min=1,max=100,dive"`
+	// AllOrNothing rolls back the whole batch if any product is not found.
+	AllOrNothing bool `json:"all_or_nothing"`
+}
+
+// BatchLikes likes and unlikes up to 100 products for the authenticated user
+// in one transaction, and reports the result of each operation. In
+// all_or_nothing mode a missing product rolls back the batch and the response
+// is 409 with committed set to false.
+func BatchLikes(dbConn *sql.DB, updates *live.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var request likeBatchRequest
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		operations := make([]db.LikeOperation, len(request.Operations))
+		for i, operation := range request.Operations {
+			operations[i] = db.LikeOperation{ProductID: operation.ProductID, Like: operation.Action == "like"}
+		}
+
+		results, err := db.ApplyLikeBatch(c.Request.Context(), dbConn, user.ID, operations, request.AllOrNothing)
+		if errors.Is(err, db.ErrLikeBatchAborted) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":     "Some products were not found, no changes were made",
+				"committed": false,
+				"results":   results,
+			})
+			return
+		}
+		if err != nil {
+			log.Println("Error applying like batch:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the likes"})
+			return
+		}
+
+		for _, result := range results {
+			if result.Result == db.LikeResultLiked || result.Result == db.LikeResultUnliked {
+				updates.Publish(c.Request.Context(), live.LikeCountUpdate(result.ProductID, result.LikeCount))
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"committed": true, "results": results})
+	}
+}
+
+// annotateLikedByMe sets LikedByMe on the products for the authenticated
+// user, if the request asked for it with include=liked_by_me and carries a
+// valid token. It returns false after writing an error response.
+func annotateLikedByMe(c *gin.Context, dbConn *sql.DB, products []models.Product) bool {
+	user := auth.GetUserFromContext(c)
+	if user == nil || c.Query("include") != "liked_by_me" || len(products) == 0 {
+		return true
+	}
+
+	productIDs := make([]int64, len(products))
+	for i, product := range products {
+		productIDs[i] = int64(product.ID)
+	}
+
+	statuses, err := db.GetLikeStatuses(c.Request.Context(), dbConn, user.ID, productIDs)
+	if err != nil {
+		log.Println("Error fetching like statuses:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return false
+	}
+
+	for i := range products {
+		liked := statuses[int64(products[i].ID)]
+		products[i].LikedByMe = &liked
+	}
+	return true
+}
+
+// PutAvailabilityAlerts opts the authenticated user in to notifications when
+// a product they like comes back in stock, opens for preorder or becomes
+// purchasable.
+func PutAvailabilityAlerts(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setAvailabilityAlerts(c, dbConn, true)
+	}
+}
+
+// DeleteAvailabilityAlerts opts the authenticated user out of availability
+// notifications for a product they like.
+func DeleteAvailabilityAlerts(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setAvailabilityAlerts(c, dbConn, false)
+	}
+}
+
+// setAvailabilityAlerts turns availability notifications for a liked product
+// on or off and writes the resulting state.
+func setAvailabilityAlerts(c *gin.Context, dbConn *sql.DB, enabled bool) {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	productID, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+
+	if err := db.SetAvailabilityAlerts(c.Request.Context(), dbConn, user.ID, productID, enabled); err != nil {
+		if errors.Is(err, db.ErrProductNotLiked) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Liked product not found"})
+			return
+		}
+		log.Println("Error updating availability alerts:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"product_id": productID, "availability_alerts": enabled})
+}
+
+// setProductLike likes or unlikes a product and writes the resulting state.
+func setProductLike(c *gin.Context, dbConn *sql.DB, updates *live.Hub, like bool) {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	productID, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+
+	var (
+		state db.LikeState
+		err   error
+	)
+	if like {
+		state, err = db.AddProductLike(c.Request.Context(), dbConn, uint64(user.ID), uint64(productID))
+	} else {
+		state, err = db.UnlikeProduct(c.Request.Context(), dbConn, uint64(user.ID), uint64(productID))
+	}
+	if err != nil {
+		respondLikeError(c, err, "Failed to update the like")
+		return
+	}
+	publishLikeCount(c, updates, state)
+
+	c.JSON(http.StatusOK, state)
+}
+
+// publishLikeCount tells the watchers of a product about its new like count,
+// if the like or unlike changed it.
+func publishLikeCount(c *gin.Context, updates *live.Hub, state db.LikeState) {
+	if state.Changed {
+		updates.Publish(c.Request.Context(), live.LikeCountUpdate(state.ProductID, state.LikeCount))
+	}
+}
+
+// respondLikeError writes the response for an error from liking or unliking a product.
+func respondLikeError(c *gin.Context, err error, message string) {
+	if errors.Is(err, db.ErrProductNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	log.Println("Error updating like:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// parseProductID reads the product ID from the URL parameters. On invalid
+// input it writes the error response and returns false.
+func parseProductID(c *gin.Context) (int64, bool) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return 0, false
+	}
+	return productID, true
+}
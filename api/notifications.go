@@ -0,0 +1,105 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications retrieves a page of the authenticated user's
+// notifications, newest first. status=pending, status=delivered or
+// status=failed narrows them down to the ones waiting for delivery, already
+// delivered, or given up on after too many failed attempts.
+func GetNotifications(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		status := c.DefaultQuery("status", "all")
+		switch status {
+		case models.NotificationPending, models.NotificationDelivered, models.NotificationFailed:
+		case "all":
+			status = ""
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status parameter, use pending, delivered, failed or all"})
+			return
+		}
+
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		notifications, totalCount, err := db.ListNotifications(c.Request.Context(), dbConn, user.ID, status, page, limit)
+		if err != nil {
+			log.Println("Error fetching notifications:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"notifications": notifications, "total_count": totalCount})
+	}
+}
+
+// GetNotificationSettings retrieves the authenticated user's alert preferences.
+func GetNotificationSettings(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		settings, err := db.GetNotificationSettings(c.Request.Context(), dbConn, user.ID)
+		if err != nil {
+			log.Println("Error fetching notification settings:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"settings": settings})
+	}
+}
+
+// UpdateNotificationSettings replaces the authenticated user's alert
+// preferences. price_drop_min_rate only alerts about price drops that leave
+// the product at least that many percent off its origin price.
+func UpdateNotificationSettings(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var request struct {
+			PriceDropEnabled *bool    `json:"price_drop_enabled" binding:"required"`
+			PriceDropMinRate *float64 `json:"price_drop_min_rate" binding:"required,min=0,max=100"`
+		}
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		settings := models.NotificationSettings{
+			UserID:           user.ID,
+			PriceDropEnabled: *request.PriceDropEnabled,
+			PriceDropMinRate: *request.PriceDropMinRate,
+		}
+		if err := db.SaveNotificationSettings(c.Request.Context(), dbConn, settings); err != nil {
+			log.Println("Error saving notification settings:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save notification settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Notification settings updated successfully", "settings": settings})
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// parsePagination reads the page and limit query parameters. On invalid input
+// it writes the error response and returns false.
+func parsePagination(c *gin.Context) (int, int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+		return 0, 0, false
+	}
+
+	limit, ok := parseLimit(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
+// parseLimit reads the limit query parameter, for lists that are not paged.
+// On invalid input it writes the error response and returns false.
+func parseLimit(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return 0, false
+	}
+	return limit, true
+}
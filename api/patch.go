@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// mergePatch applies a JSON Merge Patch (RFC 7396) to target and returns the
+// result. Objects are merged recursively, null removes a member and any other
+// value replaces the target.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// decodeJSONObject decodes r into a generic JSON object, keeping numbers as
+// json.Number so large integers survive a round trip.
+func decodeJSONObject(r io.Reader) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// toJSONObject converts v to a generic JSON object.
+func toJSONObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONObject(bytes.NewReader(data))
+}
+
+// fromJSONObject converts a generic JSON object into v.
+func fromJSONObject(object map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// The examples of RFC 7396, appendix A.
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target+" + "+tt.patch, func(t *testing.T) {
+			var target, patch interface{}
+			if err := json.Unmarshal([]byte(tt.target), &target); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.Marshal(mergePatch(target, patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("mergePatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONObject(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"object", `{"name":"Mug","in_stock":true}`, `{"in_stock":true,"name":"Mug"}`, false},
+		{"large integer", `{"origin_price":9007199254740993}`, `{"origin_price":9007199254740993}`, false},
+		{"null", `null`, `null`, false},
+		{"array", `[{"name":"Mug"}]`, ``, true},
+		{"string", `"Mug"`, ``, true},
+		{"malformed", `{"name":`, ``, true},
+		{"empty", ``, ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := decodeJSONObject(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeJSONObject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := json.Marshal(object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("decodeJSONObject() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONObjectRoundTrip(t *testing.T) {
+	type product struct {
+		Name        string `json:"name"`
+		OriginPrice int64  `json:"origin_price"`
+	}
+	want := product{Name: "Mug", OriginPrice: 9007199254740993}
+
+	object, err := toJSONObject(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got product
+	if err := fromJSONObject(object, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
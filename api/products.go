@@ -0,0 +1,517 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/auth"
+	"product-like/pkg/cursor"
+	"product-like/pkg/live"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// GetProducts retrieves a page of products. Products can be filtered by
+// shop_id, status, in_stock, is_preorder, is_purchasable, a min_price/max_price
+// range on the discounted price and min_discounted_rate, and sorted by any of
+// db.ProductSorts. The default newest-first order pages with the cursor
+// parameter; other orders, and clients passing page, use page and limit. With
+// include=liked_by_me and a valid token, each product says whether the caller liked it.
+func GetProducts(dbConn *sql.DB, cursors *cursor.Codec) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := newQueryParser(c)
+		filter := db.ProductFilter{
+			ShopID:            query.Int64("shop_id"),
+			Status:            c.Query("status"),
+			InStock:           query.Bool("in_stock"),
+			IsPreorder:        query.Bool("is_preorder"),
+			IsPurchasable:     query.Bool("is_purchasable"),
+			MinPrice:          query.Int64("min_price"),
+			MaxPrice:          query.Int64("max_price"),
+			MinDiscountedRate: query.Float64("min_discounted_rate"),
+		}
+
+		sortOrder := c.DefaultQuery("sort", db.DefaultProductSort)
+		if _, ok := db.ProductSorts[sortOrder]; !ok {
+			query.errors["sort"] = "must be one of: " + strings.Join(productSortNames(), ", ")
+		}
+
+		_, hasCursor := c.GetQuery("cursor")
+		if hasCursor && sortOrder != db.DefaultProductSort {
+			query.errors["cursor"] = "is only supported with sort=" + db.DefaultProductSort
+		}
+
+		if !query.Valid() {
+			return
+		}
+
+		if sortOrder == db.DefaultProductSort && !usesOffsetPagination(c) {
+			getProductsKeyset(c, dbConn, cursors, filter)
+			return
+		}
+
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		products, totalCount, err := db.ListProducts(c.Request.Context(), dbConn, filter, sortOrder, page, limit)
+		if err != nil {
+			log.Println("Error fetching products:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+			return
+		}
+
+		if !annotateLikedByMe(c, dbConn, products) {
+			return
+		}
+
+		// Return the list of products as a JSON response.
+		c.JSON(http.StatusOK, gin.H{
+			"products":    products,
+			"total_count": totalCount,
+			"page":        page,
+			"limit":       limit,
+		})
+	}
+}
+
+// getProductsKeyset writes a cursor-paginated page of products, newest first.
+func getProductsKeyset(c *gin.Context, dbConn *sql.DB, cursors *cursor.Codec, filter db.ProductFilter) {
+	const scope = "products"
+	page, ok := parseKeysetPage(c, cursors, scope)
+	if !ok {
+		return
+	}
+
+	products, result, err := db.ListProductsKeyset(c.Request.Context(), dbConn, filter, page)
+	if err != nil {
+		log.Println("Error fetching products:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	if !annotateLikedByMe(c, dbConn, products) {
+		return
+	}
+
+	totalCount, err := db.CountProducts(c.Request.Context(), dbConn, filter)
+	if err != nil {
+		log.Println("Error counting products:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	nextCursor, prevCursor, err := keysetCursors(cursors, scope, result)
+	if err != nil {
+		log.Println("Error encoding cursors:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products":    products,
+		"total_count": totalCount,
+		"limit":       page.Limit,
+		"next_cursor": nextCursor,
+		"prev_cursor": prevCursor,
+	})
+}
+
+// productSortNames returns the accepted sort orders in alphabetical order.
+func productSortNames() []string {
+	names := make([]string, 0, len(db.ProductSorts))
+	for name := range db.ProductSorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// productRequest is the request body for creating and replacing products.
+type productRequest struct {
+	ShopID            *int64   `json:"shop_id" binding:"omitempty,min=1"`
+	Name              string   `json:"name" binding:"required,max=255"`
+	Description       string   `json:"description"`
+	ThumbnailURL      string   `json:"thumbnail_url" binding:"required,url"`
+	OriginPrice       *int64   `json:"origin_price" binding:"required,min=0"`
+	DiscountedPrice   *int64   `json:"discounted_price" binding:"required,min=0"`
+	DiscountedRate    *float64 `json:"discounted_rate" binding:"omitempty,min=0,max=100"`
+	Status            string   `json:"status" binding:"required,oneof=draft active inactive"`
+	InStock           bool     `json:"in_stock"`
+	IsPreorder        bool     `json:"is_preorder"`
+	IsPurchasable     bool     `json:"is_purchasable"`
+	DeliveryCondition string   `json:"delivery_condition" binding:"required,max=255"`
+	DeliveryDisplay   string   `json:"delivery_display"`
+}
+
+// validate runs the checks that span several fields.
+func (r *productRequest) validate() fieldErrors {
+	fields := fieldErrors{}
+	if *r.DiscountedPrice > *r.OriginPrice {
+		fields["discounted_price"] = "must not be greater than origin_price"
+	}
+	return fields
+}
+
+// toProduct copies the request into a product. The discount rate is derived
+// from the prices unless given explicitly.
+func (r *productRequest) toProduct(productID int64) models.Product {
+	product := models.Product{
+		ID:                uint64(productID),
+		Name:              r.Name,
+		Description:       r.Description,
+		ThumbnailURL:      r.ThumbnailURL,
+		OriginPrice:       *r.OriginPrice,
+		DiscountedPrice:   *r.DiscountedPrice,
+		Status:            r.Status,
+		InStock:           r.InStock,
+		IsPreorder:        r.IsPreorder,
+		IsPurchasable:     r.IsPurchasable,
+		DeliveryCondition: r.DeliveryCondition,
+		DeliveryDisplay:   r.DeliveryDisplay,
+	}
+	if r.ShopID != nil {
+		product.ShopID = *r.ShopID
+	}
+	if r.DiscountedRate != nil {
+		product.DiscountedRate = *r.DiscountedRate
+	} else {
+		product.DiscountedRate = discountRate(product.OriginPrice, product.DiscountedPrice)
+	}
+	return product
+}
+
+// newProductRequest creates the request that would reproduce product.
+func newProductRequest(product models.Product) productRequest {
+	request := productRequest{
+		Name:              product.Name,
+		Description:       product.Description,
+		ThumbnailURL:      product.ThumbnailURL,
+		OriginPrice:       &product.OriginPrice,
+		DiscountedPrice:   &product.DiscountedPrice,
+		DiscountedRate:    &product.DiscountedRate,
+		Status:            product.Status,
+		InStock:           product.InStock,
+		IsPreorder:        product.IsPreorder,
+		IsPurchasable:     product.IsPurchasable,
+		DeliveryCondition: product.DeliveryCondition,
+		DeliveryDisplay:   product.DeliveryDisplay,
+	}
+	if product.ShopID != 0 {
+		request.ShopID = &product.ShopID
+	}
+	return request
+}
+
+// discountRate returns the discount in percent, rounded to two decimals.
+func discountRate(originPrice, discountedPrice int64) float64 {
+	if originPrice <= 0 {
+		return 0
+	}
+	rate := float64(originPrice-discountedPrice) / float64(originPrice) * 100
+	return math.Round(rate*100) / 100
+}
+
+// bindProductRequest binds and validates a productRequest. On invalid input it
+// writes the error response and returns false.
+func bindProductRequest(c *gin.Context, request *productRequest) bool {
+	if !bindJSON(c, request) {
+		return false
+	}
+	if fields := request.validate(); len(fields) > 0 {
+		respondValidationErrors(c, fields)
+		return false
+	}
+	return true
+}
+
+// CreateProduct creates a new product.
+func CreateProduct(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request productRequest
+		if !bindProductRequest(c, &request) {
+			return
+		}
+
+		product := request.toProduct(0)
+
+		// Shop staff may only create products for their own shops.
+		if !authorizeShop(c, productShopID(product)) {
+			return
+		}
+
+		// Insert the new product into the database.
+		if err := db.CreateProduct(c.Request.Context(), dbConn, &product); err != nil {
+			if errors.Is(err, db.ErrShopNotFound) {
+				respondValidationErrors(c, fieldErrors{"shop_id": "shop does not exist"})
+				return
+			}
+			log.Println("Error creating product:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the product"})
+			return
+		}
+
+		// Return a success response with the created product.
+		c.JSON(http.StatusCreated, gin.H{"message": "Product created successfully", "product_id": product.ID, "product": product})
+	}
+}
+
+// UpdateProduct replaces an existing product.
+func UpdateProduct(dbConn *sql.DB, updates *live.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Parse the product ID from the URL parameters
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+			return
+		}
+
+		// Shop staff may only manage products of their own shops.
+		if !authorizeProduct(c, dbConn, productID) {
+			return
+		}
+
+		var request productRequest
+		if !bindProductRequest(c, &request) {
+			return
+		}
+
+		product := request.toProduct(productID)
+
+		// Moving the product to another shop requires access to that shop as well.
+		if !authorizeShop(c, productShopID(product)) {
+			return
+		}
+
+		// Update the product in the database.
+		if err := db.UpdateProduct(c.Request.Context(), dbConn, &product); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			case errors.Is(err, db.ErrShopNotFound):
+				respondValidationErrors(c, fieldErrors{"shop_id": "shop does not exist"})
+			default:
+				log.Println("Error updating product:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the product"})
+			}
+			return
+		}
+
+		updates.Publish(c.Request.Context(), live.ProductUpdate(product))
+
+		// Return a success response with the updated product.
+		c.JSON(http.StatusOK, gin.H{"message": "Product updated successfully", "product": product})
+	}
+}
+
+// PatchProduct partially updates a product with a JSON Merge Patch (RFC 7396).
+// Only the fields present in the patch are written; null resets optional
+// fields. The patched product must pass the same validation as UpdateProduct.
+func PatchProduct(dbConn *sql.DB, updates *live.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Parse the product ID from the URL parameters
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+			return
+		}
+
+		if contentType := c.ContentType(); contentType != "application/merge-patch+json" && contentType != "application/json" {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Use Content-Type application/merge-patch+json"})
+			return
+		}
+
+		patch, err := decodeJSONObject(c.Request.Body)
+		if err != nil || patch == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Patch must be a JSON object"})
+			return
+		}
+
+		// The patch is merged, validated and authorized against the locked
+		// row, so concurrent patches can't combine into an invalid product
+		changed := false
+		product, err := db.PatchProduct(c.Request.Context(), dbConn, productID, func(current models.Product) (models.Product, []string, error) {
+			product, columns, err := applyProductPatch(c, current, patch)
+			changed = len(columns) > 0
+			return product, columns, err
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, errPatchRejected):
+			case errors.Is(err, sql.ErrNoRows):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			case errors.Is(err, db.ErrShopNotFound):
+				respondValidationErrors(c, fieldErrors{"shop_id": "shop does not exist"})
+			default:
+				log.Println("Error patching product:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the product"})
+			}
+			return
+		}
+
+		if changed {
+			updates.Publish(c.Request.Context(), live.ProductUpdate(product))
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Product updated successfully", "product": product})
+	}
+}
+
+// errPatchRejected is returned by applyProductPatch when it refused the patch
+// and already wrote the error response.
+var errPatchRejected = errors.New("product patch rejected")
+
+// applyProductPatch merges patch into the current product, validates the
+// result and checks that the authenticated user may manage the product both
+// in its current shop and in the one it is moved to. It returns the patched
+// product and the columns to write, or errPatchRejected once it wrote the
+// error response.
+func applyProductPatch(c *gin.Context, current models.Product, patch map[string]interface{}) (models.Product, []string, error) {
+	// Shop staff may only manage products of their own shops.
+	if !authorizeShop(c, productShopID(current)) {
+		return models.Product{}, nil, errPatchRejected
+	}
+
+	document, err := toJSONObject(newProductRequest(current))
+	if err != nil {
+		return models.Product{}, nil, err
+	}
+
+	// Only fields of the product request can be patched
+	unknown := fieldErrors{}
+	columns := make([]string, 0, len(patch))
+	for field := range patch {
+		if _, ok := document[field]; !ok {
+			unknown[field] = "cannot be changed"
+			continue
+		}
+		columns = append(columns, field)
+	}
+	if len(unknown) > 0 {
+		respondValidationErrors(c, unknown)
+		return models.Product{}, nil, errPatchRejected
+	}
+	if len(columns) == 0 {
+		return current, nil, nil
+	}
+	sort.Strings(columns)
+
+	var request productRequest
+	if err := fromJSONObject(mergePatch(document, patch).(map[string]interface{}), &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return models.Product{}, nil, errPatchRejected
+	}
+
+	// Keep the discount rate in line with patched prices unless it is patched too
+	_, ratePatched := patch["discounted_rate"]
+	_, originPatched := patch["origin_price"]
+	_, discountedPatched := patch["discounted_price"]
+	if !ratePatched && (originPatched || discountedPatched) {
+		request.DiscountedRate = nil
+		columns = append(columns, "discounted_rate")
+	}
+
+	if err := binding.Validator.ValidateStruct(&request); err != nil {
+		respondBindingError(c, err)
+		return models.Product{}, nil, errPatchRejected
+	}
+	if fields := request.validate(); len(fields) > 0 {
+		respondValidationErrors(c, fields)
+		return models.Product{}, nil, errPatchRejected
+	}
+
+	product := request.toProduct(int64(current.ID))
+
+	// Moving the product to another shop requires access to that shop as well.
+	if !authorizeShop(c, productShopID(product)) {
+		return models.Product{}, nil, errPatchRejected
+	}
+
+	return product, columns, nil
+}
+
+// DeleteProduct deletes a product by its ID.
+func DeleteProduct(dbConn *sql.DB, updates *live.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Parse the product ID from the URL parameters
+		productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+			return
+		}
+
+		// Shop staff may only manage products of their own shops.
+		if !authorizeProduct(c, dbConn, productID) {
+			return
+		}
+
+		// Delete the product from the database
+		rowsAffected, err := db.DeleteProduct(c.Request.Context(), dbConn, productID)
+		if err != nil {
+			log.Println("Error deleting product:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete the product"})
+			return
+		}
+
+		// Check if the product was deleted successfully
+		if rowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+
+		updates.Publish(c.Request.Context(), live.DeletedUpdate(productID))
+
+		// Return a success response
+		c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+	}
+}
+
+// authorizeProduct checks that the authenticated user may manage the product.
+// Otherwise it writes the error response and returns false.
+func authorizeProduct(c *gin.Context, dbConn *sql.DB, productID int64) bool {
+	shopID, err := db.GetProductShopID(c.Request.Context(), dbConn, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return false
+		}
+		log.Println("Error fetching product shop:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return false
+	}
+
+	return authorizeShop(c, shopID)
+}
+
+// productShopID returns the shop of a product, which is not valid for products without a shop.
+func productShopID(product models.Product) sql.NullInt64 {
+	return sql.NullInt64{Int64: product.ShopID, Valid: product.ShopID != 0}
+}
+
+// authorizeShop checks that the authenticated user may manage products of the
+// shop. Products without a shop can only be managed by admins. Otherwise it
+// writes the error response and returns false.
+func authorizeShop(c *gin.Context, shopID sql.NullInt64) bool {
+	user := auth.GetUserFromContext(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return false
+	}
+
+	if user.Role == models.RoleAdmin || (shopID.Valid && user.CanManageShop(shopID.Int64)) {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage products of this shop"})
+	return false
+}
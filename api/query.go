@@ -0,0 +1,103 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryParser reads optional typed query parameters, collecting an error for
+// every parameter that does not parse.
+type queryParser struct {
+	c      *gin.Context
+	errors fieldErrors
+}
+
+func newQueryParser(c *gin.Context) *queryParser {
+	return &queryParser{c: c, errors: fieldErrors{}}
+}
+
+// Int64 returns the parameter as an int64, or nil if it is absent or invalid.
+func (p *queryParser) Int64(name string) *int64 {
+	value, ok := p.c.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		p.errors[name] = "must be an integer"
+		return nil
+	}
+	return &parsed
+}
+
+// Float64 returns the parameter as a float64, or nil if it is absent or invalid.
+func (p *queryParser) Float64(name string) *float64 {
+	value, ok := p.c.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		p.errors[name] = "must be a number"
+		return nil
+	}
+	return &parsed
+}
+
+// Bool returns the parameter as a bool, or nil if it is absent or invalid.
+func (p *queryParser) Bool(name string) *bool {
+	value, ok := p.c.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		p.errors[name] = "must be true or false"
+		return nil
+	}
+	return &parsed
+}
+
+// Time returns the parameter as a time, given as an RFC 3339 timestamp or a
+// date (midnight UTC), or nil if it is absent or invalid.
+func (p *queryParser) Time(name string) *time.Time {
+	value, ok := p.c.GetQuery(name)
+	if !ok {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed
+		}
+	}
+	p.errors[name] = "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"
+	return nil
+}
+
+// OneOf returns the parameter if it is one of allowed, or def if it is absent.
+func (p *queryParser) OneOf(name, def string, allowed ...string) string {
+	value, ok := p.c.GetQuery(name)
+	if !ok {
+		return def
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return value
+		}
+	}
+	p.errors[name] = "must be one of: " + strings.Join(allowed, ", ")
+	return def
+}
+
+// Valid reports whether every parameter parsed. Otherwise it writes the error
+// response and returns false.
+func (p *queryParser) Valid() bool {
+	if len(p.errors) > 0 {
+		respondValidationErrors(p.c, p.errors)
+		return false
+	}
+	return true
+}
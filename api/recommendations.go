@@ -0,0 +1,89 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAlsoLikedProducts retrieves the products most often liked by the users
+// who liked a product, up to limit of them. Products that are not active or
+// cannot be bought are left out, and with a valid token so are those the
+// caller already likes.
+func GetAlsoLikedProducts(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, ok := parseProductID(c)
+		if !ok {
+			return
+		}
+
+		limit, ok := parseLimit(c)
+		if !ok {
+			return
+		}
+
+		if _, err := db.GetProduct(c.Request.Context(), dbConn, productID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+				return
+			}
+			log.Println("Error fetching product:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommendations"})
+			return
+		}
+
+		var userID int64
+		if user := auth.GetUserFromContext(c); user != nil {
+			userID = user.ID
+		}
+
+		products, err := db.ListAlsoLikedProducts(c.Request.Context(), dbConn, productID, userID, limit)
+		if err != nil {
+			log.Println("Error fetching also liked products:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommendations"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"products": products})
+	}
+}
+
+// GetRecommendations retrieves up to limit products for the authenticated
+// user, based on what the users with similar likes liked. Users without
+// enough likes for that get trending products instead; source says which
+// one the list came from.
+func GetRecommendations(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		limit, ok := parseLimit(c)
+		if !ok {
+			return
+		}
+
+		source := models.RecommendationSourceSimilarLikes
+		products, err := db.ListRecommendedProducts(c.Request.Context(), dbConn, user.ID, limit)
+		if err == nil && len(products) == 0 {
+			source = models.RecommendationSourceTrending
+			products, err = db.ListTrendingRecommendations(c.Request.Context(), dbConn, user.ID, limit)
+		}
+		if err != nil {
+			log.Println("Error fetching recommendations:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommendations"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"products": products, "source": source})
+	}
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// shopRequest is the request body for creating and updating shops.
+type shopRequest struct {
+	Name    string `json:"name" binding:"required,max=255"`
+	Slug    string `json:"slug" binding:"max=191"`
+	LogoURL string `json:"logo_url" binding:"omitempty,url"`
+	Status  string `json:"status" binding:"omitempty,oneof=active inactive"`
+}
+
+// apply validates the bound request and copies it into shop. On invalid
+// input it writes the error response and returns false.
+func (r *shopRequest) apply(c *gin.Context, shop *models.Shop) bool {
+	// Derive the slug from the name if none is given
+	if r.Slug == "" {
+		r.Slug = strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(r.Name), "-"), "-")
+	}
+	if !slugPattern.MatchString(r.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid slug, use lowercase letters, digits and dashes"})
+		return false
+	}
+
+	if r.Status == "" {
+		r.Status = models.ShopStatusActive
+	}
+
+	shop.Name = r.Name
+	shop.Slug = r.Slug
+	shop.LogoURL = r.LogoURL
+	shop.Status = r.Status
+	return true
+}
+
+// GetShops retrieves a list of shops.
+func GetShops(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		shops, totalCount, err := db.ListShops(c.Request.Context(), dbConn, c.Query("status"), page, limit)
+		if err != nil {
+			log.Println("Error fetching shops:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shops"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"shops": shops, "total_count": totalCount})
+	}
+}
+
+// GetShop retrieves a shop by its ID.
+func GetShop(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := parseShopID(c)
+		if !ok {
+			return
+		}
+
+		shop, err := db.GetShop(c.Request.Context(), dbConn, shopID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+				return
+			}
+			log.Println("Error fetching shop:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the shop"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"shop": shop})
+	}
+}
+
+// GetShopProducts retrieves the products of a shop.
+func GetShopProducts(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := parseShopID(c)
+		if !ok {
+			return
+		}
+
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		if _, err := db.GetShop(c.Request.Context(), dbConn, shopID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+				return
+			}
+			log.Println("Error fetching shop:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+			return
+		}
+
+		products, totalCount, err := db.ListShopProducts(c.Request.Context(), dbConn, shopID, page, limit)
+		if err != nil {
+			log.Println("Error fetching shop products:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"products": products, "total_count": totalCount})
+	}
+}
+
+// CreateShop creates a new shop.
+func CreateShop(dbConn *sql.DB, authMiddleware *auth.AuthMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request struct {
+			shopRequest
+			OwnerID *int64 `json:"owner_id"`
+		}
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var shop models.Shop
+		if !request.apply(c, &shop) {
+			return
+		}
+		shop.OwnerID = request.OwnerID
+
+		if err := db.CreateShop(c.Request.Context(), dbConn, &shop); err != nil {
+			switch {
+			case errors.Is(err, db.ErrSlugTaken):
+				c.JSON(http.StatusConflict, gin.H{"error": "Shop slug already taken"})
+			case errors.Is(err, db.ErrOwnerNotFound):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Owner not found"})
+			default:
+				log.Println("Error creating shop:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the shop"})
+			}
+			return
+		}
+
+		// The owner's role and shops changed, don't serve them from the cache
+		if shop.OwnerID != nil {
+			authMiddleware.InvalidateUser(*shop.OwnerID)
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Shop created successfully", "shop": shop})
+	}
+}
+
+// UpdateShop updates an existing shop. Shop staff may update their own shops.
+func UpdateShop(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := parseShopID(c)
+		if !ok {
+			return
+		}
+
+		if user := auth.GetUserFromContext(c); user == nil || !user.CanManageShop(shopID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage this shop"})
+			return
+		}
+
+		var request shopRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		shop := models.Shop{ID: shopID}
+		if !request.apply(c, &shop) {
+			return
+		}
+
+		if err := db.UpdateShop(c.Request.Context(), dbConn, &shop); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+			case errors.Is(err, db.ErrSlugTaken):
+				c.JSON(http.StatusConflict, gin.H{"error": "Shop slug already taken"})
+			default:
+				log.Println("Error updating shop:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the shop"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Shop updated successfully", "shop": shop})
+	}
+}
+
+// DeleteShop deletes a shop by its ID. Shops that still have products cannot
+// be deleted; products that were deleted do not count.
+func DeleteShop(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := parseShopID(c)
+		if !ok {
+			return
+		}
+
+		rowsAffected, err := db.DeleteShop(c.Request.Context(), dbConn, shopID)
+		if err != nil {
+			if errors.Is(err, db.ErrShopHasProducts) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Shop still has products"})
+				return
+			}
+			log.Println("Error deleting shop:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete the shop"})
+			return
+		}
+
+		if rowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Shop deleted successfully"})
+	}
+}
+
+// authorizeShopManagement reads the shop ID from the URL and checks that the
+// authenticated user manages the shop and that it exists. Otherwise it writes
+// the error response and returns false.
+func authorizeShopManagement(c *gin.Context, dbConn *sql.DB) (int64, bool) {
+	shopID, ok := parseShopID(c)
+	if !ok {
+		return 0, false
+	}
+
+	if user := auth.GetUserFromContext(c); user == nil || !user.CanManageShop(shopID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to manage this shop"})
+		return 0, false
+	}
+
+	if _, err := db.GetShop(c.Request.Context(), dbConn, shopID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+			return 0, false
+		}
+		log.Println("Error fetching shop:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the shop"})
+		return 0, false
+	}
+	return shopID, true
+}
+
+// parseShopID reads the shop ID from the URL parameters. On invalid input it
+// writes the error response and returns false.
+func parseShopID(c *gin.Context) (int64, bool) {
+	shopID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		return 0, false
+	}
+	return shopID, true
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"product-like/db"
+	"product-like/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrendingProducts retrieves a page of the products liked most recently,
+// ranked by their trending score. The ranking is rebuilt periodically in the
+// background, so it lags behind the latest likes by up to the refresh
+// interval. Products can be narrowed down by shop_id and status; only active
+// products are shown unless another status is asked for.
+func GetTrendingProducts(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := newQueryParser(c)
+		filter := db.ProductFilter{
+			ShopID: query.Int64("shop_id"),
+			Status: query.OneOf("status", models.ProductStatusActive,
+				models.ProductStatusDraft, models.ProductStatusActive, models.ProductStatusInactive),
+		}
+		if !query.Valid() {
+			return
+		}
+
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		products, totalCount, err := db.ListTrendingProducts(c.Request.Context(), dbConn, filter, page, limit)
+		if err != nil {
+			log.Println("Error fetching trending products:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending products"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"products":    products,
+			"total_count": totalCount,
+			"page":        page,
+			"limit":       limit,
+		})
+	}
+}
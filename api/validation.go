@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report validation errors using the JSON field names clients send
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// fieldErrors maps JSON field names to what is wrong with them.
+type fieldErrors map[string]string
+
+// respondValidationErrors writes a 400 response listing the invalid fields.
+func respondValidationErrors(c *gin.Context, fields fieldErrors) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": fields})
+}
+
+// bindJSON binds the request body into obj and validates it. On invalid input
+// it writes the error response, with field-level details where possible, and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondBindingError(c, err)
+		return false
+	}
+	return true
+}
+
+// respondBindingError writes the response for an error returned by binding or
+// validating a request, with field-level details where possible.
+func respondBindingError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fields := fieldErrors{}
+	for _, fieldErr := range validationErrs {
+		fields[fieldErr.Field()] = describeFieldError(fieldErr)
+	}
+	respondValidationErrors(c, fields)
+}
+
+func describeFieldError(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max", "lte":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email address"
+	}
+	return "is invalid"
+}
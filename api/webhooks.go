@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhooks retrieves the webhook subscriptions of a shop.
+func GetWebhooks(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := authorizeShopManagement(c, dbConn)
+		if !ok {
+			return
+		}
+
+		subscriptions, err := db.ListWebhookSubscriptions(c.Request.Context(), dbConn, shopID)
+		if err != nil {
+			log.Println("Error fetching webhooks:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"webhooks": subscriptions})
+	}
+}
+
+// CreateWebhook subscribes a URL to like events of a shop's products. The URL
+// must be https and point to a public address. Without a secret in the
+// request one is generated; either way the secret is only returned in this
+// response.
+func CreateWebhook(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := authorizeShopManagement(c, dbConn)
+		if !ok {
+			return
+		}
+
+		var request struct {
+			URL        string   `json:"url" binding:"required,url,max=2048"`
+			EventTypes []string `json:"event_types" binding:"required,min=1,unique,dive,oneof=product_liked product_unliked"`
+			Secret     string   `json:"secret" binding:"omitempty,min=16,max=128"`
+		}
+		if !bindJSON(c, &request) {
+			return
+		}
+
+		if err := webhook.ValidateURL(c.Request.Context(), request.URL); err != nil {
+			respondValidationErrors(c, fieldErrors{"url": err.Error()})
+			return
+		}
+
+		if request.Secret == "" {
+			secret, err := randomToken(32)
+			if err != nil {
+				log.Println("Error generating webhook secret:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the webhook"})
+				return
+			}
+			request.Secret = secret
+		}
+
+		subscription := models.WebhookSubscription{
+			ShopID:     shopID,
+			URL:        request.URL,
+			EventTypes: request.EventTypes,
+			Secret:     request.Secret,
+			Active:     true,
+		}
+		if err := db.CreateWebhookSubscription(c.Request.Context(), dbConn, &subscription); err != nil {
+			log.Println("Error creating webhook:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the webhook"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Webhook created successfully", "webhook": subscription})
+	}
+}
+
+// DeleteWebhook deletes one of a shop's webhook subscriptions and its delivery log.
+func DeleteWebhook(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shopID, ok := authorizeShopManagement(c, dbConn)
+		if !ok {
+			return
+		}
+
+		subscriptionID, ok := parseIDParam(c, "webhook_id", "Invalid webhook ID")
+		if !ok {
+			return
+		}
+
+		rowsAffected, err := db.DeleteWebhookSubscription(c.Request.Context(), dbConn, shopID, subscriptionID)
+		if err != nil {
+			log.Println("Error deleting webhook:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete the webhook"})
+			return
+		}
+
+		if rowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+	}
+}
+
+// GetWebhookDeliveries retrieves a page of a webhook's delivery log, newest
+// first. status=pending, delivered or dead narrows it down.
+func GetWebhookDeliveries(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subscription, ok := loadWebhook(c, dbConn)
+		if !ok {
+			return
+		}
+
+		status := c.Query("status")
+		switch status {
+		case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status parameter, use pending, delivered or dead"})
+			return
+		}
+
+		page, limit, ok := parsePagination(c)
+		if !ok {
+			return
+		}
+
+		deliveries, totalCount, err := db.ListWebhookDeliveries(c.Request.Context(), dbConn, subscription.ID, status, page, limit)
+		if err != nil {
+			log.Println("Error fetching webhook deliveries:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "total_count": totalCount})
+	}
+}
+
+// RedeliverWebhook queues a delivery to be sent again right away, e.g. one
+// that was dead-lettered after the receiver was down for too long.
+func RedeliverWebhook(dbConn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subscription, ok := loadWebhook(c, dbConn)
+		if !ok {
+			return
+		}
+
+		deliveryID, ok := parseIDParam(c, "delivery_id", "Invalid delivery ID")
+		if !ok {
+			return
+		}
+
+		delivery, err := db.RedeliverWebhook(c.Request.Context(), dbConn, subscription.ID, deliveryID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+				return
+			}
+			log.Println("Error redelivering webhook:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeliver the webhook"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "Delivery queued", "delivery": delivery})
+	}
+}
+
+// loadWebhook loads the webhook subscription named in the URL after checking
+// that the authenticated user manages its shop. Otherwise it writes the error
+// response and returns false.
+func loadWebhook(c *gin.Context, dbConn *sql.DB) (models.WebhookSubscription, bool) {
+	shopID, ok := authorizeShopManagement(c, dbConn)
+	if !ok {
+		return models.WebhookSubscription{}, false
+	}
+
+	subscriptionID, ok := parseIDParam(c, "webhook_id", "Invalid webhook ID")
+	if !ok {
+		return models.WebhookSubscription{}, false
+	}
+
+	subscription, err := db.GetWebhookSubscription(c.Request.Context(), dbConn, shopID, subscriptionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return models.WebhookSubscription{}, false
+		}
+		log.Println("Error fetching webhook:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the webhook"})
+		return models.WebhookSubscription{}, false
+	}
+	return subscription, true
+}
+
+// parseIDParam reads an ID from the URL parameter name. On invalid input it
+// writes message as the error response and returns false.
+func parseIDParam(c *gin.Context, name, message string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,40 @@
+// Command compute-similarities rebuilds the product similarities behind the
+// "users who liked this also liked" recommendations from the likes in the
+// database. It is meant to run periodically, e.g. nightly from cron.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+
+	"product-like/db"
+	"product-like/pkg/config"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	minCoLikes := flag.Int("min-co-likes", 2, "minimum number of users who liked both products of a pair")
+	perProduct := flag.Int("per-product", 50, "number of similar products to keep for each product")
+	flag.Parse()
+
+	if *minCoLikes < 1 || *perProduct < 1 {
+		log.Fatal("-min-co-likes and -per-product must be at least 1")
+	}
+
+	dbConn, err := sql.Open("postgres", config.DatabaseURL())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dbConn.Close()
+
+	stored, err := db.RefreshProductSimilarities(context.Background(), dbConn, *minCoLikes, *perProduct)
+	if err != nil {
+		log.Fatalf("Error computing product similarities: %v", err)
+	}
+
+	fmt.Printf("Stored %d similar product pairs\n", stored)
+}
@@ -0,0 +1,55 @@
+// Command reconcile-likes compares each product's stored like count with the
+// number of its favorites and reports the products that drifted. With -fix it
+// also recomputes their counts.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+
+	"product-like/db"
+	"product-like/pkg/config"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "recompute the like counts that drifted")
+	flag.Parse()
+
+	dbConn, err := sql.Open("postgres", config.DatabaseURL())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dbConn.Close()
+
+	ctx := context.Background()
+	drifts, err := db.FindLikeCountDrift(ctx, dbConn)
+	if err != nil {
+		log.Fatalf("Error finding like count drift: %v", err)
+	}
+
+	for _, drift := range drifts {
+		fmt.Printf("product %d: stored %d, actual %d\n", drift.ProductID, drift.StoredCount, drift.ActualCount)
+		if !*fix {
+			continue
+		}
+		count, err := db.RecountLikes(ctx, dbConn, drift.ProductID)
+		if err != nil {
+			log.Fatalf("Error recounting likes of product %d: %v", drift.ProductID, err)
+		}
+		fmt.Printf("product %d: like count set to %d\n", drift.ProductID, count)
+	}
+
+	switch {
+	case len(drifts) == 0:
+		fmt.Println("All like counts are consistent")
+	case *fix:
+		fmt.Printf("Fixed %d drifted like counts\n", len(drifts))
+	default:
+		fmt.Printf("Found %d drifted like counts, run with -fix to repair them\n", len(drifts))
+	}
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"product-like/models"
+)
+
+// insertLikeEvent logs a like or unlike for the like analytics.
+func (q *Queries) insertLikeEvent(ctx context.Context, userID, productID int64, liked bool) error {
+	query := `
+        INSERT INTO like_events (user_id, product_id, liked)
+        VALUES ($1, $2, $3)
+    `
+	_, err := q.db.ExecContext(ctx, query, userID, productID, liked)
+	return err
+}
+
+// GetShopLikeAnalytics retrieves the like activity of a shop's products from
+// from until to: the totals, every product's stats, and a time series with a
+// bucket per interval (one of the models.AnalyticsInterval* constants),
+// including the empty ones. Buckets start at UTC midnight, weeks on Mondays.
+// Deleted products are left out. Everything is read from one snapshot, so the
+// parts add up.
+func GetShopLikeAnalytics(ctx context.Context, db *sql.DB, shopID int64, interval string, from, to time.Time) (models.LikeAnalytics, error) {
+	analytics := models.LikeAnalytics{ShopID: shopID, Interval: interval, From: from, To: to}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return models.LikeAnalytics{}, err
+	}
+	defer tx.Rollback()
+
+	totalsQuery := `
+        SELECT COUNT(*) FILTER (WHERE e.liked),
+               COUNT(*) FILTER (WHERE NOT e.liked),
+               COUNT(DISTINCT e.user_id) FILTER (WHERE e.liked)
+        FROM like_events e
+        INNER JOIN products p ON p.id = e.product_id
+        WHERE p.shop_id = $1 AND p.deleted_at IS NULL AND e.created_at >= $2 AND e.created_at < $3
+    `
+	totals := &analytics.Totals
+	if err := tx.QueryRowContext(ctx, totalsQuery, shopID, from, to).Scan(
+		&totals.Likes, &totals.Unlikes, &totals.UniqueLikers,
+	); err != nil {
+		return models.LikeAnalytics{}, err
+	}
+	totals.SetRatio()
+
+	if analytics.Products, err = listProductLikeStats(ctx, tx, shopID, from, to); err != nil {
+		return models.LikeAnalytics{}, err
+	}
+	if analytics.Series, err = listLikeBuckets(ctx, tx, shopID, interval, from, to); err != nil {
+		return models.LikeAnalytics{}, err
+	}
+
+	return analytics, tx.Commit()
+}
+
+// listProductLikeStats retrieves the like activity of each of a shop's
+// products from from until to, most liked first.
+func listProductLikeStats(ctx context.Context, tx *sql.Tx, shopID int64, from, to time.Time) ([]models.ProductLikeStats, error) {
+	query := `
+        SELECT p.id, COALESCE(p.name, ''), p.like_count,
+               COUNT(e.id) FILTER (WHERE e.liked),
+               COUNT(e.id) FILTER (WHERE NOT e.liked),
+               COUNT(DISTINCT e.user_id) FILTER (WHERE e.liked)
+        FROM products p
+        LEFT JOIN like_events e ON e.product_id = p.id AND e.created_at >= $2 AND e.created_at < $3
+        WHERE p.shop_id = $1 AND p.deleted_at IS NULL
+        GROUP BY p.id
+        ORDER BY p.like_count DESC, p.id DESC
+    `
+	rows, err := tx.QueryContext(ctx, query, shopID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.ProductLikeStats{}
+	for rows.Next() {
+		var stats models.ProductLikeStats
+		if err := rows.Scan(
+			&stats.ProductID, &stats.Name, &stats.LikeCount, &stats.Likes, &stats.Unlikes, &stats.UniqueLikers,
+		); err != nil {
+			return nil, err
+		}
+		stats.SetRatio()
+		products = append(products, stats)
+	}
+	return products, rows.Err()
+}
+
+// listLikeBuckets retrieves the likes and unlikes of a shop's products per
+// interval from from until to, oldest first.
+func listLikeBuckets(ctx context.Context, tx *sql.Tx, shopID int64, interval string, from, to time.Time) ([]models.LikeBucket, error) {
+	query := `
+        WITH buckets AS (
+            SELECT bucket
+            FROM generate_series(
+                date_trunc($2::TEXT, $3::TIMESTAMPTZ AT TIME ZONE 'UTC'),
+                $4::TIMESTAMPTZ AT TIME ZONE 'UTC',
+                ('1 ' || $2::TEXT)::INTERVAL
+            ) AS bucket
+            WHERE bucket < $4::TIMESTAMPTZ AT TIME ZONE 'UTC'
+        ), activity AS (
+            SELECT date_trunc($2::TEXT, e.created_at AT TIME ZONE 'UTC') AS bucket,
+                   COUNT(*) FILTER (WHERE e.liked) AS likes,
+                   COUNT(*) FILTER (WHERE NOT e.liked) AS unlikes
+            FROM like_events e
+            INNER JOIN products p ON p.id = e.product_id
+            WHERE p.shop_id = $1 AND p.deleted_at IS NULL AND e.created_at >= $3 AND e.created_at < $4
+            GROUP BY 1
+        )
+        SELECT b.bucket, COALESCE(a.likes, 0), COALESCE(a.unlikes, 0)
+        FROM buckets b
+        LEFT JOIN activity a ON a.bucket = b.bucket
+        ORDER BY b.bucket
+    `
+	rows, err := tx.QueryContext(ctx, query, shopID, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.LikeBucket{}
+	for rows.Next() {
+		var bucket models.LikeBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Likes, &bucket.Unlikes); err != nil {
+			return nil, err
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
@@ -0,0 +1,398 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"product-like/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrCollectionNameTaken is returned when the user already has a collection with the name.
+	ErrCollectionNameTaken = errors.New("collection name already taken")
+	// ErrCollectionNameReserved is returned when naming a collection after the default collection.
+	ErrCollectionNameReserved = errors.New("collection name reserved")
+	// ErrProductNotLiked is returned when placing a product the user does not like into a collection.
+	ErrProductNotLiked = errors.New("product not liked")
+)
+
+// collectionColumns selects a collection aliased c along with its product
+// count; the default collection counts all of the user's likes.
+const collectionColumns = `c.id, c.user_id, c.name, c.is_default, c.position,
+    CASE
+        WHEN c.is_default THEN (SELECT COUNT(*) FROM favorites f WHERE f.user_id = c.user_id)
+        ELSE (SELECT COUNT(*) FROM collection_items ci WHERE ci.collection_id = c.id)
+    END,
+    c.share_slug, c.shared_at, c.created_at, c.updated_at`
+
+// EnsureDefaultCollection creates the user's default collection unless it exists.
+func EnsureDefaultCollection(ctx context.Context, db *sql.DB, userID int64) error {
+	query := `
+        INSERT INTO collections (user_id, name, is_default)
+        VALUES ($1, $2, TRUE)
+        ON CONFLICT (user_id) WHERE is_default DO NOTHING
+    `
+	_, err := db.ExecContext(ctx, query, userID, models.DefaultCollectionName)
+	return err
+}
+
+// ListCollections retrieves the user's collections, the default one first and
+// the others in their chosen order.
+func ListCollections(ctx context.Context, db *sql.DB, userID int64) ([]models.Collection, error) {
+	if err := EnsureDefaultCollection(ctx, db, userID); err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT ` + collectionColumns + `
+        FROM collections c
+        WHERE c.user_id = $1
+        ORDER BY c.is_default DESC, c.position, c.id
+    `
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		collection, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, *collection)
+	}
+	return collections, rows.Err()
+}
+
+// GetCollection retrieves one of the user's collections. It returns
+// sql.ErrNoRows if the collection does not exist or belongs to someone else.
+func GetCollection(ctx context.Context, db *sql.DB, userID, collectionID int64) (*models.Collection, error) {
+	query := `
+        SELECT ` + collectionColumns + `
+        FROM collections c
+        WHERE c.id = $1 AND c.user_id = $2
+    `
+	return scanCollection(db.QueryRowContext(ctx, query, collectionID, userID))
+}
+
+// CreateCollection inserts a new collection after the user's other
+// collections and fills in its ID, position and timestamps. It returns
+// ErrCollectionNameReserved for the name of the default collection.
+func CreateCollection(ctx context.Context, db *sql.DB, collection *models.Collection) error {
+	if collection.Name == models.DefaultCollectionName {
+		return ErrCollectionNameReserved
+	}
+
+	query := `
+        INSERT INTO collections (user_id, name, position)
+        SELECT $1, $2, COALESCE(MAX(position), 0) + 1
+        FROM collections
+        WHERE user_id = $1
+        RETURNING id, position, created_at, updated_at
+    `
+	err := db.QueryRowContext(ctx, query, collection.UserID, collection.Name).
+		Scan(&collection.ID, &collection.Position, &collection.CreatedAt, &collection.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrCollectionNameTaken
+	}
+	return err
+}
+
+// RenameCollection renames one of the user's collections. It returns
+// sql.ErrNoRows if the collection does not exist or belongs to someone else,
+// and ErrCollectionNameReserved when giving another collection the name of
+// the default collection.
+func RenameCollection(ctx context.Context, db *sql.DB, userID, collectionID int64, name string) error {
+	if name == models.DefaultCollectionName {
+		// Only the default collection may take its name back
+		defaultQuery := `
+            SELECT is_default
+            FROM collections
+            WHERE id = $1 AND user_id = $2
+        `
+		var isDefault bool
+		if err := db.QueryRowContext(ctx, defaultQuery, collectionID, userID).Scan(&isDefault); err != nil {
+			return err
+		}
+		if !isDefault {
+			return ErrCollectionNameReserved
+		}
+	}
+
+	query := `
+        UPDATE collections
+        SET name = $3, updated_at = NOW()
+        WHERE id = $1 AND user_id = $2
+    `
+	result, err := db.ExecContext(ctx, query, collectionID, userID, name)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrCollectionNameTaken
+		}
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReorderCollections puts the user's collections in the given order. The
+// default collection always comes first and cannot be moved. It returns
+// sql.ErrNoRows, changing nothing, if any of the IDs is not one of the user's
+// other collections.
+func ReorderCollections(ctx context.Context, db *sql.DB, userID int64, collectionIDs []int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE collections c
+        SET position = o.position, updated_at = NOW()
+        FROM unnest($2::BIGINT[]) WITH ORDINALITY AS o (id, position)
+        WHERE c.id = o.id AND c.user_id = $1 AND NOT c.is_default
+    `
+	result, err := tx.ExecContext(ctx, query, userID, pq.Array(collectionIDs))
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != int64(len(collectionIDs)) {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// DeleteCollection deletes one of the user's collections, leaving the likes in
+// it alone. The default collection cannot be deleted.
+func DeleteCollection(ctx context.Context, db *sql.DB, userID, collectionID int64) (int64, error) {
+	query := `
+        DELETE FROM collections
+        WHERE id = $1 AND user_id = $2 AND NOT is_default
+    `
+	result, err := db.ExecContext(ctx, query, collectionID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ShareCollection makes one of the user's collections public under slug and
+// returns the slug it is shared under. If the collection already is shared it
+// keeps its slug, unless regenerate is set, which replaces the slug so that
+// the old link stops working. It returns sql.ErrNoRows if the collection does
+// not exist or belongs to someone else.
+func ShareCollection(ctx context.Context, db *sql.DB, userID, collectionID int64, slug string, regenerate bool) (string, error) {
+	query := `
+        UPDATE collections
+        SET share_slug = CASE WHEN $4 THEN $3 ELSE COALESCE(share_slug, $3) END,
+            shared_at = CASE WHEN $4 OR share_slug IS NULL THEN NOW() ELSE shared_at END,
+            updated_at = NOW()
+        WHERE id = $1 AND user_id = $2
+        RETURNING share_slug
+    `
+	var shareSlug string
+	err := db.QueryRowContext(ctx, query, collectionID, userID, slug, regenerate).Scan(&shareSlug)
+	return shareSlug, err
+}
+
+// UnshareCollection revokes the public link of one of the user's collections.
+// It returns sql.ErrNoRows if the collection does not exist or belongs to someone else.
+func UnshareCollection(ctx context.Context, db *sql.DB, userID, collectionID int64) error {
+	query := `
+        UPDATE collections
+        SET share_slug = NULL, shared_at = NULL, updated_at = NOW()
+        WHERE id = $1 AND user_id = $2
+    `
+	result, err := db.ExecContext(ctx, query, collectionID, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetSharedCollection retrieves the collection shared under slug. It returns
+// sql.ErrNoRows if no collection is shared under it.
+func GetSharedCollection(ctx context.Context, db *sql.DB, slug string) (*models.Collection, error) {
+	query := `
+        SELECT ` + collectionColumns + `
+        FROM collections c
+        WHERE c.share_slug = $1
+    `
+	return scanCollection(db.QueryRowContext(ctx, query, slug))
+}
+
+// ListSharedCollectionProducts retrieves a page of the products in a shared
+// collection, most recently added first, and their total number. Deleted
+// products are left out, as the viewers never saw them.
+func ListSharedCollectionProducts(ctx context.Context, db *sql.DB, collection *models.Collection, page, limit int) ([]models.SharedProduct, int, error) {
+	// The default collection holds all of the user's likes
+	from, where, orderBy, arg := `
+        FROM collection_items ci
+        INNER JOIN favorites f ON f.id = ci.favorite_id
+        INNER JOIN products p ON p.id = f.product_id`, `ci.collection_id = $1`, `ci.created_at DESC, ci.favorite_id DESC`, collection.ID
+	if collection.IsDefault {
+		from, where, orderBy, arg = `
+        FROM favorites f
+        INNER JOIN products p ON p.id = f.product_id`, `f.user_id = $1`, `f.created_at DESC, f.id DESC`, collection.UserID
+	}
+
+	query := `
+        SELECT ` + productColumns + from + `
+        WHERE ` + where + ` AND p.deleted_at IS NULL
+        ORDER BY ` + orderBy + `
+        LIMIT $2
+        OFFSET ($3 - 1) * $2
+    `
+	rows, err := db.QueryContext(ctx, query, arg, limit, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []models.SharedProduct{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		products = append(products, models.NewSharedProduct(product))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var totalCount int
+	countQuery := `
+        SELECT COUNT(*)` + from + `
+        WHERE ` + where + ` AND p.deleted_at IS NULL
+    `
+	if err := db.QueryRowContext(ctx, countQuery, arg).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	return products, totalCount, nil
+}
+
+// AddCollectionProduct places a product the user likes into one of their
+// collections, unless it already is there, and reports whether it was added.
+// It returns ErrProductNotLiked if the user does not like the product. The
+// caller must make sure the collection belongs to the user.
+func AddCollectionProduct(ctx context.Context, db *sql.DB, userID, collectionID, productID int64) (bool, error) {
+	query := `
+        WITH favorite AS (
+            SELECT id
+            FROM favorites
+            WHERE user_id = $1 AND product_id = $3
+        ), inserted AS (
+            INSERT INTO collection_items (collection_id, favorite_id)
+            SELECT $2, id
+            FROM favorite
+            ON CONFLICT DO NOTHING
+            RETURNING favorite_id
+        )
+        SELECT EXISTS (SELECT 1 FROM favorite), EXISTS (SELECT 1 FROM inserted)
+    `
+	var liked, inserted bool
+	if err := db.QueryRowContext(ctx, query, userID, collectionID, productID).Scan(&liked, &inserted); err != nil {
+		return false, err
+	}
+	if !liked {
+		return false, ErrProductNotLiked
+	}
+	return inserted, nil
+}
+
+// RemoveCollectionProduct takes a product out of one of the user's
+// collections, keeping the like, and reports whether it was there.
+func RemoveCollectionProduct(ctx context.Context, db *sql.DB, userID, collectionID, productID int64) (bool, error) {
+	query := `
+        DELETE FROM collection_items ci
+        USING favorites f
+        WHERE ci.favorite_id = f.id AND ci.collection_id = $2
+            AND f.user_id = $1 AND f.product_id = $3
+    `
+	result, err := db.ExecContext(ctx, query, userID, collectionID, productID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ListCollectionProducts retrieves a page of the products in a collection,
+// most recently added first, and the total number of products in it. It does
+// not cover the default collection, whose products are the user's liked products.
+func ListCollectionProducts(ctx context.Context, db *sql.DB, collectionID int64, page, limit int) ([]models.LikedProduct, int, error) {
+	query := `
+        SELECT ` + productColumns + `, f.id, f.created_at
+        FROM collection_items ci
+        INNER JOIN favorites f ON f.id = ci.favorite_id
+        INNER JOIN products p ON p.id = f.product_id
+        WHERE ci.collection_id = $1
+        ORDER BY ci.created_at DESC, ci.favorite_id DESC
+        LIMIT $2
+        OFFSET ($3 - 1) * $2
+    `
+	rows, err := db.QueryContext(ctx, query, collectionID, limit, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	products, _, err := scanLikedProducts(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var totalCount int
+	countQuery := `
+        SELECT COUNT(*)
+        FROM collection_items
+        WHERE collection_id = $1
+    `
+	if err := db.QueryRowContext(ctx, countQuery, collectionID).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	return products, totalCount, nil
+}
+
+func scanCollection(row scanner) (*models.Collection, error) {
+	var (
+		collection models.Collection
+		shareSlug  sql.NullString
+		sharedAt   sql.NullTime
+	)
+	if err := row.Scan(
+		&collection.ID, &collection.UserID, &collection.Name, &collection.IsDefault, &collection.Position,
+		&collection.ProductCount, &shareSlug, &sharedAt, &collection.CreatedAt, &collection.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	collection.ShareSlug = shareSlug.String
+	if sharedAt.Valid {
+		collection.SharedAt = &sharedAt.Time
+	}
+	return &collection, nil
+}
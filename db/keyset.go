@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Keyset is a position in a list ordered by (created_at, id).
+type Keyset struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// KeysetPage selects a page of a list ordered newest first, or oldest first
+// if OldestFirst is set. Without a cursor it selects the first page. With one
+// it selects the rows after the cursor, or the rows before it when Backward is set.
+type KeysetPage struct {
+	Cursor      *Keyset
+	Backward    bool
+	OldestFirst bool
+	Limit       int
+}
+
+// KeysetResult describes where a fetched page sits in the list.
+type KeysetResult struct {
+	First   Keyset
+	Last    Keyset
+	HasNext bool
+	HasPrev bool
+}
+
+// clause returns the condition selecting the rows past the cursor, the ORDER
+// BY to fetch them in, and the cursor arguments. createdAt and id name the
+// key columns; placeholders are numbered from argOffset+1. The query should
+// fetch Limit+1 rows so finishKeysetPage can tell whether more rows follow.
+func (p KeysetPage) clause(createdAt, id string, argOffset int) (string, string, []interface{}) {
+	// Walking backwards fetches in the opposite order; finishKeysetPage
+	// restores the list order afterwards
+	direction, comparison := "DESC", "<"
+	if p.OldestFirst != p.Backward {
+		direction, comparison = "ASC", ">"
+	}
+	orderBy := fmt.Sprintf("%s %s, %s %s", createdAt, direction, id, direction)
+
+	if p.Cursor == nil {
+		return "TRUE", orderBy, nil
+	}
+	condition := fmt.Sprintf("(%s, %s) %s ($%d, $%d)", createdAt, id, comparison, argOffset+1, argOffset+2)
+	return condition, orderBy, []interface{}{p.Cursor.CreatedAt, p.Cursor.ID}
+}
+
+// finishKeysetPage trims the extra row fetched to detect more rows, restores
+// the list order for backward pages and reports the page's position.
+func finishKeysetPage[T any](p KeysetPage, items []T, keys []Keyset) ([]T, KeysetResult) {
+	more := len(items) > p.Limit
+	if more {
+		items, keys = items[:p.Limit], keys[:p.Limit]
+	}
+
+	if p.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	var result KeysetResult
+	if p.Backward {
+		result.HasNext = p.Cursor != nil
+		result.HasPrev = more
+	} else {
+		result.HasNext = more
+		result.HasPrev = p.Cursor != nil
+	}
+
+	if len(keys) > 0 {
+		result.First, result.Last = keys[0], keys[len(keys)-1]
+	} else if p.Cursor != nil {
+		result.First, result.Last = *p.Cursor, *p.Cursor
+	}
+	return items, result
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+// Outcomes of a single operation in a like batch.
+const (
+	LikeResultLiked        = "liked"
+	LikeResultAlreadyLiked = "already_liked"
+	LikeResultUnliked      = "unliked"
+	LikeResultNotLiked     = "not_liked"
+	LikeResultNotFound     = "not_found"
+)
+
+// ErrLikeBatchAborted is returned by ApplyLikeBatch in all-or-nothing mode
+// when an operation failed and the whole batch was rolled back.
+var ErrLikeBatchAborted = errors.New("like batch aborted")
+
+// LikeOperation likes or unlikes one product as part of a batch.
+type LikeOperation struct {
+	ProductID int64
+	Like      bool
+}
+
+// LikeOperationResult is the outcome of one operation of a batch.
+type LikeOperationResult struct {
+	ProductID int64  `json:"product_id"`
+	Result    string `json:"result"`
+	LikeCount int64  `json:"like_count"`
+}
+
+// ApplyLikeBatch applies the operations for the user in a single transaction
+// and returns one result per operation, in order. Operations on products that
+// do not exist are reported as not_found; in all-or-nothing mode they roll the
+// whole batch back and ApplyLikeBatch returns the results along with
+// ErrLikeBatchAborted.
+func ApplyLikeBatch(ctx context.Context, db *sql.DB, userID int64, operations []LikeOperation, allOrNothing bool) ([]LikeOperationResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := New(db).WithTx(tx)
+
+	// Lock products in ID order, so concurrent batches cannot deadlock on
+	// their like counts
+	order := make([]int, len(operations))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return operations[order[i]].ProductID < operations[order[j]].ProductID
+	})
+
+	results := make([]LikeOperationResult, len(operations))
+	failed := false
+	for _, i := range order {
+		operation := operations[i]
+		results[i].ProductID = operation.ProductID
+
+		state, err := q.setLike(ctx, userID, operation.ProductID, operation.Like)
+		if errors.Is(err, ErrProductNotFound) {
+			results[i].Result = LikeResultNotFound
+			failed = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results[i].Result = likeResult(state)
+		results[i].LikeCount = state.LikeCount
+	}
+
+	if failed && allOrNothing {
+		return results, ErrLikeBatchAborted
+	}
+	return results, tx.Commit()
+}
+
+// likeResult names the outcome of a like or unlike.
+func likeResult(state LikeState) string {
+	switch {
+	case state.Liked && state.Changed:
+		return LikeResultLiked
+	case state.Liked:
+		return LikeResultAlreadyLiked
+	case state.Changed:
+		return LikeResultUnliked
+	default:
+		return LikeResultNotLiked
+	}
+}
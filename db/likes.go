@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ErrProductNotFound is returned when liking or unliking a product that does not exist.
+var ErrProductNotFound = errors.New("product not found")
+
+// LikeState is the state of a user's like of a product after liking or unliking it.
+type LikeState struct {
+	ProductID int64 `json:"product_id"`
+	Liked     bool  `json:"liked"`
+	// Changed is false if the product already was in the requested state.
+	Changed   bool  `json:"changed"`
+	LikeCount int64 `json:"like_count"`
+}
+
+// InsertLike records that the user likes the product, unless they already do,
+// in a single statement so concurrent likes cannot race. It reports whether a
+// like was added, and returns ErrProductNotFound if the product does not
+// exist or was deleted.
+func (q *Queries) InsertLike(ctx context.Context, userID, productID int64) (bool, error) {
+	query := `
+        WITH product AS (
+            SELECT id
+            FROM products
+            WHERE id = $2 AND deleted_at IS NULL
+        ), inserted AS (
+            INSERT INTO favorites (user_id, product_id, created_at)
+            SELECT $1, id, NOW()
+            FROM product
+            ON CONFLICT (user_id, product_id) DO NOTHING
+            RETURNING id
+        )
+        SELECT EXISTS (SELECT 1 FROM product), EXISTS (SELECT 1 FROM inserted)
+    `
+	var found, inserted bool
+	if err := q.db.QueryRowContext(ctx, query, userID, productID).Scan(&found, &inserted); err != nil {
+		if isForeignKeyViolation(err) {
+			return false, ErrProductNotFound
+		}
+		return false, err
+	}
+	if !found {
+		return false, ErrProductNotFound
+	}
+	return inserted, nil
+}
+
+// DeleteLike removes the user's like of the product, if any, in a single
+// statement. It reports whether a like was removed, and returns
+// ErrProductNotFound if the product never existed. Likes of deleted products
+// can still be removed.
+func (q *Queries) DeleteLike(ctx context.Context, userID, productID int64) (bool, error) {
+	query := `
+        WITH deleted AS (
+            DELETE FROM favorites
+            WHERE user_id = $1 AND product_id = $2
+            RETURNING id
+        )
+        SELECT EXISTS (SELECT 1 FROM products WHERE id = $2), EXISTS (SELECT 1 FROM deleted)
+    `
+	var found, deleted bool
+	if err := q.db.QueryRowContext(ctx, query, userID, productID).Scan(&found, &deleted); err != nil {
+		return false, err
+	}
+	if !found {
+		return false, ErrProductNotFound
+	}
+	return deleted, nil
+}
+
+// GetLikeStatuses reports for each of the given products whether the user
+// likes it, in a single query. Every requested ID is in the returned map.
+func GetLikeStatuses(ctx context.Context, db *sql.DB, userID int64, productIDs []int64) (map[int64]bool, error) {
+	statuses := make(map[int64]bool, len(productIDs))
+	for _, productID := range productIDs {
+		statuses[productID] = false
+	}
+	if len(productIDs) == 0 {
+		return statuses, nil
+	}
+
+	query := `
+        SELECT product_id
+        FROM favorites
+        WHERE user_id = $1 AND product_id = ANY($2)
+    `
+	rows, err := db.QueryContext(ctx, query, userID, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int64
+		if err := rows.Scan(&productID); err != nil {
+			return nil, err
+		}
+		statuses[productID] = true
+	}
+	return statuses, rows.Err()
+}
+
+// AdjustLikeCount adds delta to a product's like count and returns the new count.
+func (q *Queries) AdjustLikeCount(ctx context.Context, productID, delta int64) (int64, error) {
+	query := `
+        UPDATE products
+        SET like_count = like_count + $2
+        WHERE id = $1
+        RETURNING like_count
+    `
+	var count int64
+	err := q.db.QueryRowContext(ctx, query, productID, delta).Scan(&count)
+	return count, err
+}
+
+// GetLikeCount retrieves a product's like count.
+func (q *Queries) GetLikeCount(ctx context.Context, productID int64) (int64, error) {
+	query := `
+        SELECT like_count
+        FROM products
+        WHERE id = $1
+    `
+	var count int64
+	err := q.db.QueryRowContext(ctx, query, productID).Scan(&count)
+	return count, err
+}
+
+// LikeCountDrift is a product whose stored like count differs from its favorites.
+type LikeCountDrift struct {
+	ProductID   int64
+	StoredCount int64
+	ActualCount int64
+}
+
+// FindLikeCountDrift lists the products whose like_count does not match the
+// number of their favorites.
+func FindLikeCountDrift(ctx context.Context, db *sql.DB) ([]LikeCountDrift, error) {
+	query := `
+        SELECT p.id, p.like_count, COALESCE(f.count, 0)
+        FROM products p
+        LEFT JOIN (
+            SELECT product_id, COUNT(*) AS count
+            FROM favorites
+            GROUP BY product_id
+        ) AS f ON f.product_id = p.id
+        WHERE p.like_count <> COALESCE(f.count, 0)
+        ORDER BY p.id
+    `
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drifts []LikeCountDrift
+	for rows.Next() {
+		var drift LikeCountDrift
+		if err := rows.Scan(&drift.ProductID, &drift.StoredCount, &drift.ActualCount); err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, drift)
+	}
+	return drifts, rows.Err()
+}
+
+// RecountLikes recomputes a product's like count from its favorites and
+// returns the new count. The product row is locked first, so likes committed
+// concurrently are either counted or applied on top of the new count.
+func RecountLikes(ctx context.Context, db *sql.DB, productID int64) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, productID); err != nil {
+		return 0, err
+	}
+
+	query := `
+        UPDATE products
+        SET like_count = (SELECT COUNT(*) FROM favorites WHERE product_id = $1)
+        WHERE id = $1
+        RETURNING like_count
+    `
+	var count int64
+	if err := tx.QueryRowContext(ctx, query, productID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
@@ -0,0 +1,7 @@
+-- deleted_at used to default to now(), which made every user look deleted.
+-- Make it a real soft-delete marker: NULL until the user is deleted.
+ALTER TABLE users
+    ALTER COLUMN deleted_at DROP NOT NULL,
+    ALTER COLUMN deleted_at DROP DEFAULT;
+
+UPDATE users SET deleted_at = NULL;
@@ -0,0 +1,17 @@
+-- refresh tokens; only a SHA-256 hash of the opaque token is stored.
+-- Tokens issued from the same login share a family_id so that replaying an
+-- already-rotated token can revoke the whole chain.
+CREATE TABLE refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    replaced_by BIGINT,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT fk_refresh_token_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT unique_refresh_token_hash UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
@@ -0,0 +1,11 @@
+-- roles: shopper (default), shop_staff, admin
+ALTER TABLE users ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'shopper';
+
+-- shop_staff links staff users to the shops whose products they may manage
+CREATE TABLE shop_staff (
+    user_id BIGINT NOT NULL,
+    shop_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (user_id, shop_id),
+    CONSTRAINT fk_shop_staff_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
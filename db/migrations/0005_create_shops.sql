@@ -0,0 +1,33 @@
+-- shops table
+CREATE TABLE shops (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(191) NOT NULL,
+    logo_url TEXT,
+    status VARCHAR(191) NOT NULL,
+    owner_id BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT fk_shop_owner FOREIGN KEY (owner_id) REFERENCES users(id),
+    CONSTRAINT unique_shop_slug UNIQUE (slug)
+);
+
+-- Create placeholder shops for shop IDs that are already referenced, so the
+-- foreign keys below can be added without losing data.
+INSERT INTO shops (id, name, slug, status)
+SELECT shop_id, 'Shop ' || shop_id, 'shop-' || shop_id, 'active'
+FROM (
+    SELECT shop_id FROM products WHERE shop_id IS NOT NULL
+    UNION
+    SELECT shop_id FROM shop_staff
+) AS referenced;
+
+SELECT setval(pg_get_serial_sequence('shops', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM shops;
+
+ALTER TABLE products
+    ADD CONSTRAINT fk_shop FOREIGN KEY (shop_id) REFERENCES shops(id);
+
+ALTER TABLE shop_staff
+    ADD CONSTRAINT fk_shop_staff_shop FOREIGN KEY (shop_id) REFERENCES shops(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_products_shop_id ON products (shop_id);
@@ -0,0 +1,3 @@
+-- indexes backing keyset pagination on (created_at, id)
+CREATE INDEX idx_favorites_user_created_at ON favorites (user_id, created_at DESC, id DESC);
+CREATE INDEX idx_products_created_at ON products (created_at DESC, id DESC);
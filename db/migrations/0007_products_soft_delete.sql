@@ -0,0 +1,3 @@
+-- Products are soft-deleted so that likes of deleted products keep pointing at
+-- a row (fk_product) and can be shown as deleted in the user's liked list.
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMPTZ;
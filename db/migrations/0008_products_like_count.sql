@@ -0,0 +1,8 @@
+-- like_count is maintained in the same transaction as every like and unlike,
+-- so product cards can show it without counting favorites.
+ALTER TABLE products ADD COLUMN like_count BIGINT NOT NULL DEFAULT 0;
+
+UPDATE products p
+SET like_count = (SELECT COUNT(*) FROM favorites f WHERE f.product_id = p.id);
+
+CREATE INDEX idx_products_like_count ON products (like_count DESC, id DESC);
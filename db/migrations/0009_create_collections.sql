@@ -0,0 +1,30 @@
+-- collections group a user's likes into named folders. Every user has one
+-- default collection, created on first use, which holds all of their likes
+-- and has no collection_items of its own.
+CREATE TABLE collections (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    is_default BOOLEAN DEFAULT FALSE NOT NULL,
+    position INT DEFAULT 0 NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT fk_collection_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT unique_collection_name UNIQUE (user_id, name)
+);
+
+CREATE UNIQUE INDEX unique_default_collection ON collections (user_id) WHERE is_default;
+
+-- collection_items places liked products into collections. Unliking a product
+-- removes it from all collections.
+CREATE TABLE collection_items (
+    collection_id BIGINT NOT NULL,
+    favorite_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (collection_id, favorite_id),
+    CONSTRAINT fk_collection_item_collection FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    CONSTRAINT fk_collection_item_favorite FOREIGN KEY (favorite_id) REFERENCES favorites(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_collection_items_favorite_id ON collection_items (favorite_id);
+CREATE INDEX idx_collection_items_created_at ON collection_items (collection_id, created_at DESC, favorite_id DESC);
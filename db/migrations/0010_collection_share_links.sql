@@ -0,0 +1,7 @@
+-- share_slug makes a collection public: anyone with the slug can view it.
+-- Revoking the link clears the slug; regenerating replaces it.
+ALTER TABLE collections
+    ADD COLUMN share_slug VARCHAR(64),
+    ADD COLUMN shared_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX unique_collection_share_slug ON collections (share_slug) WHERE share_slug IS NOT NULL;
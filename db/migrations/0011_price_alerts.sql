@@ -0,0 +1,43 @@
+-- product_price_history records every change to a product's prices.
+CREATE TABLE product_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL,
+    old_origin_price BIGINT NOT NULL,
+    new_origin_price BIGINT NOT NULL,
+    old_discounted_price BIGINT NOT NULL,
+    new_discounted_price BIGINT NOT NULL,
+    old_discounted_rate DOUBLE PRECISION,
+    new_discounted_rate DOUBLE PRECISION,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT fk_price_history_product FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE INDEX idx_product_price_history_product_id ON product_price_history (product_id, created_at DESC);
+
+-- notification_settings holds each user's alert preferences; users without a
+-- row get the defaults.
+CREATE TABLE notification_settings (
+    user_id BIGINT PRIMARY KEY,
+    price_drop_enabled BOOLEAN DEFAULT TRUE NOT NULL,
+    -- only alert when the new price is at least this many percent off
+    price_drop_min_rate DOUBLE PRECISION DEFAULT 0 NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT fk_notification_settings_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- notifications are created in the transaction that triggers them and
+-- delivered afterwards by the notification dispatcher.
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    product_id BIGINT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    delivered_at TIMESTAMPTZ,
+    CONSTRAINT fk_notification_user FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_notification_product FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE INDEX idx_notifications_undelivered ON notifications (id) WHERE delivered_at IS NULL;
+CREATE INDEX idx_notifications_user_id ON notifications (user_id, created_at DESC);
@@ -0,0 +1,6 @@
+-- Users opt in per liked product to hear when it comes back in stock, opens
+-- for preorder or becomes purchasable again.
+ALTER TABLE favorites ADD COLUMN notify_availability BOOLEAN DEFAULT FALSE NOT NULL;
+
+-- Looked up to skip repeats of the same notification within a window
+CREATE INDEX idx_notifications_dedup ON notifications (user_id, product_id, type, created_at DESC);
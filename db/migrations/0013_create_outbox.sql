@@ -0,0 +1,16 @@
+-- outbox holds events for downstream consumers. Rows are written in the same
+-- transaction as the change they describe and published afterwards by the
+-- outbox relay, which retries failed rows with backoff until they go out.
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    product_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    last_error TEXT,
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (next_attempt_at, id) WHERE published_at IS NULL;
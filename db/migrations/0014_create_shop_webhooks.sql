@@ -0,0 +1,39 @@
+-- webhook_subscriptions let shops receive like events for their products.
+-- Payloads are signed with the subscription's secret (HMAC-SHA256).
+CREATE TABLE webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT NOT NULL,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    active BOOLEAN DEFAULT TRUE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT fk_webhook_subscription_shop FOREIGN KEY (shop_id) REFERENCES shops(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_subscriptions_shop_id ON webhook_subscriptions (shop_id);
+
+-- webhook_deliveries is both the delivery queue and the delivery log. A
+-- delivery is pending until the receiver accepts it, and dead once it ran out
+-- of attempts; dead deliveries can be redelivered manually.
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    event_id BIGINT NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) DEFAULT 'pending' NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    last_status_code INT,
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    CONSTRAINT fk_webhook_delivery_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    -- the outbox publishes at least once; this keeps one delivery per event
+    CONSTRAINT unique_webhook_delivery_event UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at, id) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, created_at DESC);
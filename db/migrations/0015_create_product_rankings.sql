@@ -0,0 +1,12 @@
+-- product_rankings holds the trending score of every product liked recently.
+-- It is rebuilt periodically by the trending refresher from favorites.created_at,
+-- so GET /api/products/trending never has to aggregate likes per request.
+CREATE TABLE product_rankings (
+    product_id BIGINT PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    recent_likes BIGINT NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_product_rankings_score ON product_rankings (score DESC, product_id DESC);
+CREATE INDEX idx_favorites_created_at ON favorites (created_at);
@@ -0,0 +1,13 @@
+-- product_similarities holds, for every product, the products most often liked
+-- by the same users, scored by the cosine similarity of their likers. It is
+-- rebuilt offline by the compute-similarities command.
+CREATE TABLE product_similarities (
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    similar_product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    co_likes BIGINT NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (product_id, similar_product_id)
+);
+
+CREATE INDEX idx_product_similarities_score ON product_similarities (product_id, score DESC, similar_product_id);
@@ -0,0 +1,16 @@
+-- like_events logs every like and unlike, since unliking deletes the favorite.
+-- It backs the like analytics of shops, such as likes and unlikes over time.
+CREATE TABLE like_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    liked BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX idx_like_events_product_created_at ON like_events (product_id, created_at);
+
+-- Likes made before the log existed; earlier unlikes are lost
+INSERT INTO like_events (user_id, product_id, liked, created_at)
+SELECT user_id, product_id, TRUE, created_at
+FROM favorites;
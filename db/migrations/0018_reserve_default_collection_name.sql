@@ -0,0 +1,6 @@
+-- The default collection's name is reserved. Collections that took it before
+-- their user's default collection existed would keep it from being created,
+-- so they get their ID appended.
+UPDATE collections
+SET name = name || ' (' || id || ')', updated_at = NOW()
+WHERE NOT is_default AND name = 'All likes';
@@ -0,0 +1,12 @@
+-- Notifications the notifier fails to deliver are retried with backoff, and
+-- given up on (failed_at) after too many attempts, so one notification that
+-- cannot be delivered does not hold up the others.
+ALTER TABLE notifications
+    ADD COLUMN attempts INT DEFAULT 0 NOT NULL,
+    ADD COLUMN next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN failed_at TIMESTAMPTZ;
+
+DROP INDEX idx_notifications_undelivered;
+CREATE INDEX idx_notifications_due ON notifications (next_attempt_at, id)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
@@ -0,0 +1,12 @@
+-- Webhook payloads used to be the whole outbox event, including the ID of the
+-- user who liked the product. Rewrite the queued and logged ones to the
+-- shop-facing payload, which leaves shoppers out.
+UPDATE webhook_deliveries
+SET payload = jsonb_build_object(
+    'event_id', payload->'id',
+    'type', payload->'type',
+    'product_id', payload->'product_id',
+    'like_count', payload->'payload'->'like_count',
+    'created_at', payload->'created_at'
+)
+WHERE payload ? 'payload';
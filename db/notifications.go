@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"product-like/models"
+)
+
+const notificationColumns = `id, user_id, product_id, type, payload, created_at, delivered_at, failed_at, attempts`
+
+// GetNotificationSettings retrieves a user's alert preferences, or the
+// defaults if the user never changed them.
+func GetNotificationSettings(ctx context.Context, db *sql.DB, userID int64) (models.NotificationSettings, error) {
+	query := `
+        SELECT price_drop_enabled, price_drop_min_rate
+        FROM notification_settings
+        WHERE user_id = $1
+    `
+	settings := models.DefaultNotificationSettings(userID)
+	err := db.QueryRowContext(ctx, query, userID).Scan(&settings.PriceDropEnabled, &settings.PriceDropMinRate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, nil
+	}
+	return settings, err
+}
+
+// SaveNotificationSettings stores a user's alert preferences.
+func SaveNotificationSettings(ctx context.Context, db *sql.DB, settings models.NotificationSettings) error {
+	query := `
+        INSERT INTO notification_settings (user_id, price_drop_enabled, price_drop_min_rate)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id) DO UPDATE
+        SET price_drop_enabled = EXCLUDED.price_drop_enabled,
+            price_drop_min_rate = EXCLUDED.price_drop_min_rate,
+            updated_at = NOW()
+    `
+	_, err := db.ExecContext(ctx, query, settings.UserID, settings.PriceDropEnabled, settings.PriceDropMinRate)
+	return err
+}
+
+// SetAvailabilityAlerts turns notifications about a liked product becoming
+// available on or off for the user. It returns ErrProductNotLiked if the user
+// does not like the product.
+func SetAvailabilityAlerts(ctx context.Context, db *sql.DB, userID, productID int64, enabled bool) error {
+	query := `
+        UPDATE favorites
+        SET notify_availability = $3
+        WHERE user_id = $1 AND product_id = $2
+    `
+	result, err := db.ExecContext(ctx, query, userID, productID, enabled)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrProductNotLiked
+	}
+	return nil
+}
+
+// ListNotifications retrieves a page of a user's notifications, newest first,
+// and their total number. If status is set, only the notifications that are
+// still pending delivery, were delivered, or were given up on are included.
+func ListNotifications(ctx context.Context, db *sql.DB, userID int64, status string, page, limit int) ([]models.Notification, int, error) {
+	where := `WHERE user_id = $1 AND (
+            $2 = ''
+            OR ($2 = '` + models.NotificationPending + `' AND delivered_at IS NULL AND failed_at IS NULL)
+            OR ($2 = '` + models.NotificationDelivered + `' AND delivered_at IS NOT NULL)
+            OR ($2 = '` + models.NotificationFailed + `' AND failed_at IS NOT NULL)
+        )`
+	query := `
+        SELECT ` + notificationColumns + `
+        FROM notifications
+        ` + where + `
+        ORDER BY created_at DESC, id DESC
+        LIMIT $3
+        OFFSET ($4 - 1) * $3
+    `
+	rows, err := db.QueryContext(ctx, query, userID, status, limit, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var totalCount int
+	countQuery := `
+        SELECT COUNT(*)
+        FROM notifications
+        ` + where
+	if err := db.QueryRowContext(ctx, countQuery, userID, status).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, totalCount, nil
+}
+
+// ClaimDueNotifications claims up to limit notifications that were not
+// delivered yet and are due, oldest first, by leasing them: their next
+// attempt is pushed back by lease, so no other dispatcher picks them up while
+// they are being delivered. The claim is committed right away, so no
+// transaction or row lock is held while delivering; the outcome of each
+// notification is recorded with MarkNotificationDelivered or
+// MarkNotificationFailed. Notifications that were given up on are left out.
+func ClaimDueNotifications(ctx context.Context, db DBTX, limit int, lease time.Duration) ([]models.Notification, error) {
+	query := `
+        UPDATE notifications
+        SET next_attempt_at = NOW() + $2::DOUBLE PRECISION * INTERVAL '1 second'
+        WHERE id IN (
+            SELECT id
+            FROM notifications
+            WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at, id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + notificationColumns + `
+    `
+	rows, err := db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationDelivered records that a notification was delivered.
+func MarkNotificationDelivered(ctx context.Context, db DBTX, notificationID int64) error {
+	query := `
+        UPDATE notifications
+        SET delivered_at = NOW(), attempts = attempts + 1, last_error = NULL
+        WHERE id = $1
+    `
+	_, err := db.ExecContext(ctx, query, notificationID)
+	return err
+}
+
+// MarkNotificationFailed records a failed attempt to deliver a notification.
+// It is retried at retryAt, unless giveUp is set, which stops delivering it.
+func MarkNotificationFailed(ctx context.Context, db DBTX, notificationID int64, reason string, retryAt time.Time, giveUp bool) error {
+	query := `
+        UPDATE notifications
+        SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3,
+            failed_at = CASE WHEN $4 THEN NOW() END
+        WHERE id = $1
+    `
+	_, err := db.ExecContext(ctx, query, notificationID, reason, retryAt, giveUp)
+	return err
+}
+
+func scanNotification(row scanner) (models.Notification, error) {
+	var (
+		notification models.Notification
+		payload      []byte
+		deliveredAt  sql.NullTime
+		failedAt     sql.NullTime
+	)
+	if err := row.Scan(
+		&notification.ID, &notification.UserID, &notification.ProductID, &notification.Type,
+		&payload, &notification.CreatedAt, &deliveredAt, &failedAt, &notification.Attempts,
+	); err != nil {
+		return models.Notification{}, err
+	}
+	notification.Payload = payload
+	if deliveredAt.Valid {
+		notification.DeliveredAt = &deliveredAt.Time
+	}
+	if failedAt.Valid {
+		notification.FailedAt = &failedAt.Time
+	}
+	return notification, nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"product-like/models"
+)
+
+const eventColumns = `id, event_type, product_id, payload, created_at, attempts`
+
+// insertEvent adds an event to the outbox. It must run in the transaction of
+// the change the event describes, so that either both or neither are stored.
+func (q *Queries) insertEvent(ctx context.Context, eventType string, productID int64, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO outbox (event_type, product_id, payload)
+        VALUES ($1, $2, $3)
+    `
+	_, err = q.db.ExecContext(ctx, query, eventType, productID, string(data))
+	return err
+}
+
+// ClaimDueEvents claims up to limit unpublished events that are due, oldest
+// first, by leasing them: their next attempt is pushed back by lease, so no
+// other relay picks them up while they are being published. The claim is
+// committed right away, so no transaction or row lock is held while
+// publishing; the outcome of each event is recorded with MarkEventPublished
+// or MarkEventFailed. An event whose relay dies is retried once the lease
+// runs out.
+func ClaimDueEvents(ctx context.Context, db DBTX, limit int, lease time.Duration) ([]models.Event, error) {
+	query := `
+        WITH claimed AS (
+            UPDATE outbox
+            SET next_attempt_at = NOW() + $2::DOUBLE PRECISION * INTERVAL '1 second'
+            WHERE id IN (
+                SELECT id
+                FROM outbox
+                WHERE published_at IS NULL AND next_attempt_at <= NOW()
+                ORDER BY id
+                LIMIT $1
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING ` + eventColumns + `
+        )
+        SELECT ` + eventColumns + `
+        FROM claimed
+        ORDER BY id
+    `
+	rows, err := db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		var (
+			event   models.Event
+			payload []byte
+		)
+		if err := rows.Scan(&event.ID, &event.Type, &event.ProductID, &payload, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// MarkEventPublished records that an event was published.
+func MarkEventPublished(ctx context.Context, db DBTX, eventID int64) error {
+	query := `
+        UPDATE outbox
+        SET published_at = NOW(), attempts = attempts + 1, last_error = NULL
+        WHERE id = $1
+    `
+	_, err := db.ExecContext(ctx, query, eventID)
+	return err
+}
+
+// MarkEventFailed records a failed attempt to publish an event and when to try again.
+func MarkEventFailed(ctx context.Context, db DBTX, eventID int64, retryAt time.Time, reason string) error {
+	query := `
+        UPDATE outbox
+        SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+        WHERE id = $1
+    `
+	_, err := db.ExecContext(ctx, query, eventID, retryAt, reason)
+	return err
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"product-like/models"
+)
+
+// AvailabilityNotificationWindow is how long after notifying a user that a
+// product became available they are not told the same again, so that flags
+// flapping during an update spree do not flood them.
+const AvailabilityNotificationWindow = 24 * time.Hour
+
+// recordProductChanges records what an update changed about a product and
+// creates the notifications it triggers. It runs in the transaction of the update.
+func (q *Queries) recordProductChanges(ctx context.Context, before, after models.Product) error {
+	if err := q.recordAvailabilityChanges(ctx, before, after); err != nil {
+		return err
+	}
+	return q.recordPriceChange(ctx, before, after)
+}
+
+// recordPriceChange records a change of a product's prices and notifies the
+// users who like it if its price dropped.
+func (q *Queries) recordPriceChange(ctx context.Context, before, after models.Product) error {
+	if before.OriginPrice == after.OriginPrice && before.DiscountedPrice == after.DiscountedPrice {
+		return nil
+	}
+
+	if err := q.insertPriceHistory(ctx, before, after); err != nil {
+		return err
+	}
+
+	// Only drops of the price customers pay are worth telling anyone about
+	if after.DiscountedPrice >= before.DiscountedPrice || after.Status != models.ProductStatusActive {
+		return nil
+	}
+	return q.insertPriceDropNotifications(ctx, before, after)
+}
+
+// insertPriceHistory records a product's old and new prices.
+func (q *Queries) insertPriceHistory(ctx context.Context, before, after models.Product) error {
+	query := `
+        INSERT INTO product_price_history (
+            product_id, old_origin_price, new_origin_price, old_discounted_price,
+            new_discounted_price, old_discounted_rate, new_discounted_rate
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	_, err := q.db.ExecContext(ctx, query,
+		after.ID, before.OriginPrice, after.OriginPrice, before.DiscountedPrice,
+		after.DiscountedPrice, before.DiscountedRate, after.DiscountedRate,
+	)
+	return err
+}
+
+// insertPriceDropNotifications notifies the users who like a product that
+// its price dropped, unless they turned price drop alerts off or the new
+// discount is below their threshold.
+func (q *Queries) insertPriceDropNotifications(ctx context.Context, before, after models.Product) error {
+	payload, err := json.Marshal(models.PriceDrop{
+		ProductName:        after.Name,
+		OldDiscountedPrice: before.DiscountedPrice,
+		NewDiscountedPrice: after.DiscountedPrice,
+		OriginPrice:        after.OriginPrice,
+		DiscountedRate:     after.DiscountedRate,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO notifications (user_id, product_id, type, payload)
+        SELECT f.user_id, f.product_id, $2, $3::JSONB
+        FROM favorites f
+        INNER JOIN users u ON u.id = f.user_id
+        LEFT JOIN notification_settings s ON s.user_id = f.user_id
+        WHERE f.product_id = $1 AND u.deleted_at IS NULL
+            AND COALESCE(s.price_drop_enabled, TRUE)
+            AND $4 >= COALESCE(s.price_drop_min_rate, 0)
+    `
+	_, err = q.db.ExecContext(ctx, query, after.ID, models.NotificationPriceDrop, string(payload), after.DiscountedRate)
+	return err
+}
+
+// recordAvailabilityChanges notifies the users who opted in on their like of
+// a product when it comes back in stock, opens for preorder or becomes
+// purchasable. Flags being turned off are not worth a notification.
+func (q *Queries) recordAvailabilityChanges(ctx context.Context, before, after models.Product) error {
+	if after.Status != models.ProductStatusActive {
+		return nil
+	}
+
+	var types []string
+	if after.InStock && !before.InStock {
+		types = append(types, models.NotificationBackInStock)
+	}
+	if after.IsPreorder && !before.IsPreorder {
+		types = append(types, models.NotificationPreorderOpen)
+	}
+	if after.IsPurchasable && !before.IsPurchasable {
+		types = append(types, models.NotificationPurchasable)
+	}
+	if len(types) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(models.AvailabilityChange{
+		ProductName:     after.Name,
+		InStock:         after.InStock,
+		IsPreorder:      after.IsPreorder,
+		IsPurchasable:   after.IsPurchasable,
+		DiscountedPrice: after.DiscountedPrice,
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO notifications (user_id, product_id, type, payload)
+        SELECT f.user_id, f.product_id, $2, $3::JSONB
+        FROM favorites f
+        INNER JOIN users u ON u.id = f.user_id
+        WHERE f.product_id = $1 AND f.notify_availability AND u.deleted_at IS NULL
+            AND NOT EXISTS (
+                SELECT 1
+                FROM notifications n
+                WHERE n.user_id = f.user_id AND n.product_id = f.product_id AND n.type = $2
+                    AND n.created_at > NOW() - $4 * INTERVAL '1 second'
+            )
+    `
+	window := AvailabilityNotificationWindow.Seconds()
+	for _, notificationType := range types {
+		if _, err := q.db.ExecContext(ctx, query, after.ID, notificationType, string(payload), window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,515 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"product-like/models"
+)
+
+// ErrShopNotFound is returned when a product refers to a shop that does not exist.
+var ErrShopNotFound = errors.New("shop not found")
+
+// productColumns lists the products columns in the order scanProduct expects.
+const productColumns = `
+    p.id, p.shop_id, p.name, p.description, p.thumbnail_url, p.origin_price,
+    p.discounted_price, p.discounted_rate, p.status, p.in_stock, p.is_preorder,
+    p.is_purchasable, p.delivery_condition, p.delivery_display, p.like_count, p.created_at,
+    p.updated_at, p.deleted_at`
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct scans a row selected with productColumns, followed by extra.
+func scanProduct(row scanner, extra ...interface{}) (models.Product, error) {
+	var (
+		product         models.Product
+		shopID          sql.NullInt64
+		name            sql.NullString
+		description     sql.NullString
+		discountedRate  sql.NullFloat64
+		deliveryDisplay sql.NullString
+		deletedAt       sql.NullTime
+	)
+	dest := []interface{}{
+		&product.ID, &shopID, &name, &description, &product.ThumbnailURL, &product.OriginPrice,
+		&product.DiscountedPrice, &discountedRate, &product.Status, &product.InStock, &product.IsPreorder,
+		&product.IsPurchasable, &product.DeliveryCondition, &deliveryDisplay, &product.LikeCount, &product.CreatedAt,
+		&product.UpdatedAt, &deletedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.Product{}, err
+	}
+	product.ShopID = shopID.Int64
+	product.Name = name.String
+	product.Description = description.String
+	product.DiscountedRate = discountedRate.Float64
+	product.DeliveryDisplay = deliveryDisplay.String
+	if deletedAt.Valid {
+		product.DeletedAt = &deletedAt.Time
+	}
+	return product, nil
+}
+
+// ProductFilter narrows down ListProducts. Nil and empty fields do not filter.
+// Deleted products are always excluded.
+type ProductFilter struct {
+	ShopID            *int64
+	Status            string
+	InStock           *bool
+	IsPreorder        *bool
+	IsPurchasable     *bool
+	MinPrice          *int64
+	MaxPrice          *int64
+	MinDiscountedRate *float64
+}
+
+// where builds the WHERE clause for the filter and its arguments. extra
+// conditions are ANDed in as they are.
+func (f ProductFilter) where(extra ...string) (string, []interface{}) {
+	var (
+		conditions = []string{"p.deleted_at IS NULL"}
+		args       []interface{}
+	)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.ShopID != nil {
+		add("p.shop_id = $%d", *f.ShopID)
+	}
+	if f.Status != "" {
+		add("p.status = $%d", f.Status)
+	}
+	if f.InStock != nil {
+		add("p.in_stock = $%d", *f.InStock)
+	}
+	if f.IsPreorder != nil {
+		add("p.is_preorder = $%d", *f.IsPreorder)
+	}
+	if f.IsPurchasable != nil {
+		add("p.is_purchasable = $%d", *f.IsPurchasable)
+	}
+	if f.MinPrice != nil {
+		add("p.discounted_price >= $%d", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		add("p.discounted_price <= $%d", *f.MaxPrice)
+	}
+	if f.MinDiscountedRate != nil {
+		add("p.discounted_rate >= $%d", *f.MinDiscountedRate)
+	}
+
+	conditions = append(conditions, extra...)
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ProductSorts maps the sort orders ListProducts accepts to their ORDER BY
+// clauses. Only these are ever interpolated into SQL. Every order ends with
+// the ID so pages are stable.
+var ProductSorts = map[string]string{
+	"newest":     "p.created_at DESC, p.id DESC",
+	"price_asc":  "p.discounted_price ASC, p.id ASC",
+	"price_desc": "p.discounted_price DESC, p.id DESC",
+	"discount":   "p.discounted_rate DESC NULLS LAST, p.id DESC",
+	"most_liked": "p.like_count DESC, p.id DESC",
+}
+
+// DefaultProductSort is the sort order used when none is requested.
+const DefaultProductSort = "newest"
+
+// ListProducts retrieves a page of the products matching filter in the given
+// sort order, and the total number of matching products.
+func ListProducts(ctx context.Context, db *sql.DB, filter ProductFilter, sort string, page, limit int) ([]models.Product, int, error) {
+	orderBy, ok := ProductSorts[sort]
+	if !ok {
+		return nil, 0, fmt.Errorf("db: unknown product sort %q", sort)
+	}
+
+	where, args := filter.where()
+
+	query := `
+        SELECT ` + productColumns + `
+        FROM products p
+        ` + where + `
+        ORDER BY ` + orderBy + fmt.Sprintf(`
+        LIMIT $%d
+        OFFSET $%d`, len(args)+1, len(args)+2)
+	rows, err := db.QueryContext(ctx, query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	totalCount, err := CountProducts(ctx, db, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return products, totalCount, nil
+}
+
+// ListProductsKeyset retrieves a page of the products matching filter, newest
+// first, using keyset pagination on (created_at, id).
+func ListProductsKeyset(ctx context.Context, db *sql.DB, filter ProductFilter, page KeysetPage) ([]models.Product, KeysetResult, error) {
+	_, filterArgs := filter.where()
+	keysetCondition, orderBy, keysetArgs := page.clause("p.created_at", "p.id", len(filterArgs))
+	where, args := filter.where(keysetCondition)
+	args = append(args, keysetArgs...)
+
+	query := `
+        SELECT ` + productColumns + `
+        FROM products p
+        ` + where + `
+        ORDER BY ` + orderBy + fmt.Sprintf(`
+        LIMIT $%d`, len(args)+1)
+	rows, err := db.QueryContext(ctx, query, append(args, page.Limit+1)...)
+	if err != nil {
+		return nil, KeysetResult{}, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	var keys []Keyset
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, KeysetResult{}, err
+		}
+		products = append(products, product)
+		keys = append(keys, Keyset{CreatedAt: product.CreatedAt, ID: int64(product.ID)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, KeysetResult{}, err
+	}
+
+	products, result := finishKeysetPage(page, products, keys)
+	return products, result, nil
+}
+
+// CountProducts counts the products matching filter.
+func CountProducts(ctx context.Context, db *sql.DB, filter ProductFilter) (int, error) {
+	where, args := filter.where()
+	query := `
+        SELECT COUNT(*)
+        FROM products p
+        ` + where
+
+	var totalCount int
+	err := db.QueryRowContext(ctx, query, args...).Scan(&totalCount)
+	return totalCount, err
+}
+
+// GetProduct retrieves a product by ID. It returns sql.ErrNoRows if the
+// product does not exist or was deleted.
+func GetProduct(ctx context.Context, db *sql.DB, productID int64) (models.Product, error) {
+	query := `
+        SELECT ` + productColumns + `
+        FROM products p
+        WHERE p.id = $1 AND p.deleted_at IS NULL
+    `
+	return scanProduct(db.QueryRowContext(ctx, query, productID))
+}
+
+// CreateProduct inserts a new product and fills in its ID and timestamps,
+// adding a product_created event to the outbox in the same transaction.
+func CreateProduct(ctx context.Context, db *sql.DB, product *models.Product) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO products (
+            shop_id, name, description, thumbnail_url, origin_price, discounted_price,
+            discounted_rate, status, in_stock, is_preorder, is_purchasable,
+            delivery_condition, delivery_display
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        RETURNING id, created_at, updated_at
+    `
+	err = tx.QueryRowContext(ctx, query, productArgs(product)...).
+		Scan(&product.ID, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrShopNotFound
+		}
+		return err
+	}
+
+	q := New(db).WithTx(tx)
+	if err := q.insertEvent(ctx, models.EventProductCreated, int64(product.ID), models.ProductEvent{Product: *product}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateProduct replaces every column of a product, refreshes its timestamps
+// and reloads the product. Price changes are recorded along with the update.
+// It returns sql.ErrNoRows if the product does not exist or was deleted.
+func UpdateProduct(ctx context.Context, db *sql.DB, product *models.Product) error {
+	query := `
+        UPDATE products AS p
+        SET shop_id = $1, name = $2, description = $3, thumbnail_url = $4,
+            origin_price = $5, discounted_price = $6, discounted_rate = $7,
+            status = $8, in_stock = $9, is_preorder = $10, is_purchasable = $11,
+            delivery_condition = $12, delivery_display = $13, updated_at = NOW()
+        WHERE p.id = $14 AND p.deleted_at IS NULL
+        RETURNING ` + productColumns
+	updated, err := changeProduct(ctx, db, int64(product.ID), func(models.Product) (string, []interface{}, error) {
+		return query, append(productArgs(product), product.ID), nil
+	})
+	if err != nil {
+		return err
+	}
+	*product = updated
+	return nil
+}
+
+// ProductPatch computes from a product as it currently is the patched product
+// and which of its columns to write.
+type ProductPatch func(current models.Product) (patched models.Product, columns []string, err error)
+
+// PatchProduct locks a product, has patch compute the change from the locked
+// row, and updates only the columns patch returns, taking their values from
+// the patched product. Concurrent patches of a product are thus applied one
+// after the other, each to the result of the one before. It refreshes
+// updated_at and returns the reloaded product; price changes are recorded
+// along with the update. If patch returns no columns, nothing is written and
+// the product is returned as it is. It returns sql.ErrNoRows if the product
+// does not exist, and the error of patch, if any, as is.
+func PatchProduct(ctx context.Context, db *sql.DB, productID int64, patch ProductPatch) (models.Product, error) {
+	return changeProduct(ctx, db, productID, func(current models.Product) (string, []interface{}, error) {
+		patched, columns, err := patch(current)
+		if err != nil || len(columns) == 0 {
+			return "", nil, err
+		}
+		patched.ID = current.ID
+		return patchProductQuery(&patched, columns)
+	})
+}
+
+// patchProductQuery builds an UPDATE of the given columns of product returning productColumns.
+func patchProductQuery(product *models.Product, columns []string) (string, []interface{}, error) {
+	args := productArgs(product)
+
+	var (
+		assignments []string
+		values      []interface{}
+	)
+	for _, column := range columns {
+		index := -1
+		for i, writable := range productWritableColumns {
+			if writable == column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return "", nil, fmt.Errorf("db: product column %q is not writable", column)
+		}
+
+		values = append(values, args[index])
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(values)))
+	}
+	assignments = append(assignments, "updated_at = NOW()")
+	values = append(values, product.ID)
+
+	query := `
+        UPDATE products AS p
+        SET ` + strings.Join(assignments, ", ") + `
+        WHERE p.id = $` + strconv.Itoa(len(values)) + ` AND p.deleted_at IS NULL
+        RETURNING ` + productColumns
+	return query, values, nil
+}
+
+// changeProduct locks a product and runs the UPDATE returning productColumns
+// that change builds from it, then returns the reloaded product. In the same
+// transaction it records what changed, comparing against the product as it
+// was before, and adds a product_updated event to the outbox. If change
+// returns no query, nothing is written and the locked product is returned.
+func changeProduct(ctx context.Context, db *sql.DB, productID int64, change func(before models.Product) (string, []interface{}, error)) (models.Product, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Product{}, err
+	}
+	defer tx.Rollback()
+
+	q := New(db).WithTx(tx)
+	before, err := q.getProductForUpdate(ctx, productID)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	query, args, err := change(before)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if query == "" {
+		return before, nil
+	}
+
+	updated, err := scanProduct(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return models.Product{}, ErrShopNotFound
+		}
+		return models.Product{}, err
+	}
+
+	if err := q.recordProductChanges(ctx, before, updated); err != nil {
+		return models.Product{}, err
+	}
+	if err := q.insertEvent(ctx, models.EventProductUpdated, int64(updated.ID), models.ProductEvent{Product: updated}); err != nil {
+		return models.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, err
+	}
+
+	return updated, nil
+}
+
+// getProductForUpdate retrieves a product and locks its row until the
+// surrounding transaction ends. It returns sql.ErrNoRows if the product does
+// not exist or was deleted.
+func (q *Queries) getProductForUpdate(ctx context.Context, productID int64) (models.Product, error) {
+	query := `
+        SELECT ` + productColumns + `
+        FROM products p
+        WHERE p.id = $1 AND p.deleted_at IS NULL
+        FOR UPDATE
+    `
+	return scanProduct(q.db.QueryRowContext(ctx, query, productID))
+}
+
+// productWritableColumns lists the product columns clients may set, in the order productArgs uses.
+var productWritableColumns = []string{
+	"shop_id", "name", "description", "thumbnail_url", "origin_price", "discounted_price",
+	"discounted_rate", "status", "in_stock", "is_preorder", "is_purchasable",
+	"delivery_condition", "delivery_display",
+}
+
+// productArgs returns the writable product columns in the order CreateProduct and UpdateProduct use.
+func productArgs(product *models.Product) []interface{} {
+	return []interface{}{
+		sql.NullInt64{Int64: product.ShopID, Valid: product.ShopID != 0},
+		product.Name,
+		nullString(product.Description),
+		product.ThumbnailURL,
+		product.OriginPrice,
+		product.DiscountedPrice,
+		product.DiscountedRate,
+		product.Status,
+		product.InStock,
+		product.IsPreorder,
+		product.IsPurchasable,
+		product.DeliveryCondition,
+		nullString(product.DeliveryDisplay),
+	}
+}
+
+// DeleteProduct soft-deletes a product. Deleted products disappear from
+// listings but stay in the liked products of users who liked them. A
+// product_deleted event is added to the outbox in the same transaction.
+func DeleteProduct(ctx context.Context, db *sql.DB, productID int64) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE products AS p
+        SET deleted_at = NOW(), updated_at = NOW()
+        WHERE p.id = $1 AND p.deleted_at IS NULL
+        RETURNING ` + productColumns
+	deleted, err := scanProduct(tx.QueryRowContext(ctx, query, productID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	q := New(db).WithTx(tx)
+	if err := q.insertEvent(ctx, models.EventProductDeleted, productID, models.ProductEvent{Product: deleted}); err != nil {
+		return 0, err
+	}
+	return 1, tx.Commit()
+}
+
+// GetProductShopID retrieves the shop a product belongs to. The result is not
+// valid for products without a shop. It returns sql.ErrNoRows if the product
+// does not exist or was deleted.
+func GetProductShopID(ctx context.Context, db *sql.DB, productID int64) (sql.NullInt64, error) {
+	var shopID sql.NullInt64
+	query := `
+        SELECT shop_id
+        FROM products
+        WHERE id = $1 AND deleted_at IS NULL
+    `
+	err := db.QueryRowContext(ctx, query, productID).Scan(&shopID)
+	return shopID, err
+}
+
+// ListShopProducts retrieves a page of a shop's products, newest first, and the shop's total product count.
+func ListShopProducts(ctx context.Context, db *sql.DB, shopID int64, page, limit int) ([]models.Product, int, error) {
+	query := `
+        SELECT ` + productColumns + `
+        FROM products p
+        WHERE p.shop_id = $1 AND p.deleted_at IS NULL
+        ORDER BY p.created_at DESC, p.id DESC
+        LIMIT $2
+        OFFSET ($3 - 1) * $2
+    `
+	rows, err := db.QueryContext(ctx, query, shopID, limit, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var totalCount int
+	countQuery := `
+        SELECT COUNT(*)
+        FROM products
+        WHERE shop_id = $1 AND deleted_at IS NULL
+    `
+	if err := db.QueryRowContext(ctx, countQuery, shopID).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	return products, totalCount, nil
+}
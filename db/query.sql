@@ -6,23 +6,39 @@ SELECT EXISTS (
     WHERE user_id = $1 AND product_id = $2
 );
 
--- Unlike a product (remove a like)
+-- Check which of the given products the user has liked
 -- db: products
-DELETE FROM favorites
-WHERE user_id = $1 AND product_id = $2;
+SELECT product_id
+FROM favorites
+WHERE user_id = $1 AND product_id = ANY($2);
+
+-- Unlike a product (remove a like), reporting whether the product exists and a like was removed
+-- db: products
+WITH deleted AS (
+    DELETE FROM favorites
+    WHERE user_id = $1 AND product_id = $2
+    RETURNING id
+)
+SELECT EXISTS (SELECT 1 FROM products WHERE id = $2), EXISTS (SELECT 1 FROM deleted);
 
 -- Retrieve liked products with pagination
 -- db: products
-SELECT p.*
-FROM products p
-INNER JOIN (
-    SELECT product_id
-    FROM favorites
-    WHERE user_id = $1
-    ORDER BY created_at 
-    LIMIT $2
-    OFFSET ($3 - 1) * $2
-) AS liked_products ON p.id = liked_products.product_id;
+SELECT p.*, f.id, f.created_at
+FROM favorites f
+INNER JOIN products p ON p.id = f.product_id
+WHERE f.user_id = $1
+ORDER BY f.created_at DESC, f.id DESC
+LIMIT $2
+OFFSET ($3 - 1) * $2;
+
+-- Retrieve liked products after a (created_at, id) cursor
+-- db: products
+SELECT p.*, f.id, f.created_at
+FROM favorites f
+INNER JOIN products p ON p.id = f.product_id
+WHERE f.user_id = $1 AND (f.created_at, f.id) < ($2, $3)
+ORDER BY f.created_at DESC, f.id DESC
+LIMIT $4;
 
 -- Retrieve the total count of liked products for a user
 -- db: products
@@ -30,8 +46,31 @@ SELECT COUNT(*)
 FROM favorites
 WHERE user_id = $1;
 
--- Add a product like (insert a record into favorites)
+-- Add a product like (insert a record into favorites) unless it exists,
+-- reporting whether the product exists and a like was added
+-- db: products
+WITH product AS (
+    SELECT id
+    FROM products
+    WHERE id = $2 AND deleted_at IS NULL
+), inserted AS (
+    INSERT INTO favorites (user_id, product_id, created_at)
+    SELECT $1, id, NOW()
+    FROM product
+    ON CONFLICT (user_id, product_id) DO NOTHING
+    RETURNING id
+)
+SELECT EXISTS (SELECT 1 FROM product), EXISTS (SELECT 1 FROM inserted);
+
+-- Adjust the like count of a product after a like or unlike
+-- db: products
+UPDATE products
+SET like_count = like_count + $2
+WHERE id = $1
+RETURNING like_count;
+
+-- Get the like count of a product
 -- db: products
-INSERT INTO favorites (user_id, product_id, created_at)
-VALUES ($1, $2, NOW())
-RETURNING id;
+SELECT like_count
+FROM products
+WHERE id = $1;
@@ -3,7 +3,9 @@ package db
 import (
 	"context"
 	"database/sql"
-	"time"
+	"fmt"
+
+	"product-like/models"
 )
 
 // CheckProductLike checks if the user has liked the product.
@@ -26,79 +28,95 @@ func CheckProductLike(ctx context.Context, db *sql.DB, userID, productID uint64)
 	return exists, nil
 }
 
-// UnlikeProduct removes the like for a product.
-func UnlikeProduct(ctx context.Context, db *sql.DB, userID, productID uint64) (int64, error) {
+// UnlikeProduct removes the user's like of a product. Unliking a product that
+// is not liked is a no-op; the returned state tells whether anything changed.
+func UnlikeProduct(ctx context.Context, db *sql.DB, userID, productID uint64) (LikeState, error) {
+	return changeLike(ctx, db, int64(userID), int64(productID), false)
+}
+
+// RetrieveLikedProducts retrieves liked products with page/limit pagination,
+// most recently liked first unless oldestFirst is set. Deleted products are
+// included. Prefer RetrieveLikedProductsKeyset, which stays fast on deep pages
+// and does not skip or repeat products while the user likes or unlikes in
+// between pages.
+func RetrieveLikedProducts(ctx context.Context, db *sql.DB, userID int, oldestFirst bool, page, limit int) ([]models.LikedProduct, int, error) {
+	direction := "DESC"
+	if oldestFirst {
+		direction = "ASC"
+	}
 	query := `
-        DELETE FROM favorites
-        WHERE user_id = $1 AND product_id = $2
+        SELECT ` + productColumns + `, f.id, f.created_at
+        FROM favorites f
+        INNER JOIN products p ON p.id = f.product_id
+        WHERE f.user_id = $1
+        ORDER BY f.created_at ` + direction + `, f.id ` + direction + `
+        LIMIT $2
+        OFFSET ($3 - 1) * $2
     `
-	result, err := db.ExecContext(ctx, query, userID, productID)
+	rows, err := db.QueryContext(ctx, query, userID, limit, page)
 	if err != nil {
-		return 0, err
+		return nil, 0, err
 	}
-	rowsAffected, err := result.RowsAffected()
+
+	products, _, err := scanLikedProducts(rows)
 	if err != nil {
-		return 0, err
+		return nil, 0, err
+	}
+
+	totalCount, err := RetrieveTotalLikedProductCount(ctx, db, userID)
+	if err != nil {
+		return nil, 0, err
 	}
-	return rowsAffected, nil
-}
 
-type Product struct {
-	ID                uint64    `db:"id"`
-	ShopID            uint64    `db:"shop_id"`
-	Name              string    `db:"name"`
-	Description       string    `db:"description"`
-	ThumbnailURL      string    `db:"thumbnail_url"`
-	OriginPrice       int64     `db:"origin_price"`
-	DiscountedPrice   int64     `db:"discounted_price"`
-	DiscountedRate    float64   `db:"discounted_rate"`
-	Status            string    `db:"status"`
-	InStock           bool      `db:"in_stock"`
-	IsPreorder        bool      `db:"is_preorder"`
-	IsPurchasable     bool      `db:"is_purchasable"`
-	DeliveryCondition string    `db:"delivery_condition"`
-	DeliveryDisplay   string    `db:"delivery_display"`
-	CreatedAt         time.Time `db:"created_at"`
-	UpdatedAt         time.Time `db:"updated_at"`
+	return products, totalCount, nil
 }
 
-// RetrieveLikedProducts retrieves liked products with pagination.
-func RetrieveLikedProducts(ctx context.Context, db *sql.DB, userID, page, limit int) ([]Product, int, error) {
-	var products []Product
+// RetrieveLikedProductsKeyset retrieves a page of liked products using keyset
+// pagination on (favorites.created_at, favorites.id). Deleted products are included.
+func RetrieveLikedProductsKeyset(ctx context.Context, db *sql.DB, userID int, page KeysetPage) ([]models.LikedProduct, KeysetResult, error) {
+	condition, orderBy, keysetArgs := page.clause("f.created_at", "f.id", 1)
 	query := `
-        SELECT p.*
-        FROM products p
-        INNER JOIN (
-            SELECT product_id
-            FROM favorites
-            WHERE user_id = $1
-            ORDER BY created_at
-            LIMIT $2
-            OFFSET ($3 - 1) * $2
-        ) AS liked_products ON p.id = liked_products.product_id
-    `
-	rows, err := db.QueryContext(ctx, query, userID, limit, page)
+        SELECT ` + productColumns + `, f.id, f.created_at
+        FROM favorites f
+        INNER JOIN products p ON p.id = f.product_id
+        WHERE f.user_id = $1 AND ` + condition + `
+        ORDER BY ` + orderBy + fmt.Sprintf(`
+        LIMIT $%d`, len(keysetArgs)+2)
+	args := append(append([]interface{}{userID}, keysetArgs...), page.Limit+1)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, KeysetResult{}, err
 	}
+
+	products, keys, err := scanLikedProducts(rows)
+	if err != nil {
+		return nil, KeysetResult{}, err
+	}
+
+	products, result := finishKeysetPage(page, products, keys)
+	return products, result, nil
+}
+
+// scanLikedProducts scans rows of productColumns followed by the favorite's
+// id and created_at, and closes rows. It also returns the favorites' keysets.
+func scanLikedProducts(rows *sql.Rows) ([]models.LikedProduct, []Keyset, error) {
 	defer rows.Close()
 
+	products := []models.LikedProduct{}
+	var keys []Keyset
 	for rows.Next() {
-		var product Product
-		if err := rows.Scan(
-			&product.ID, &product.ShopID, &product.Name, // Add other fields here
-		); err != nil {
-			return nil, 0, err
+		var key Keyset
+		product, err := scanProduct(rows, &key.ID, &key.CreatedAt)
+		if err != nil {
+			return nil, nil, err
 		}
-		products = append(products, product)
+		products = append(products, models.NewLikedProduct(product, key.ID, key.CreatedAt))
+		keys = append(keys, key)
 	}
-
-	totalCount, err := RetrieveTotalLikedProductCount(ctx, db, userID)
-	if err != nil {
-		return nil, 0, err
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
 	}
-
-	return products, totalCount, nil
+	return products, keys, nil
 }
 
 // RetrieveTotalLikedProductCount retrieves the total count of liked products for a user.
@@ -116,17 +134,68 @@ func RetrieveTotalLikedProductCount(ctx context.Context, db *sql.DB, userID int)
 	return totalCount, nil
 }
 
-// AddProductLike adds a product like (inserts a record into favorites).
-func AddProductLike(ctx context.Context, db *sql.DB, userID, productID uint64) (int64, error) {
-	query := `
-        INSERT INTO favorites (user_id, product_id, created_at)
-        VALUES ($1, $2, NOW())
-        RETURNING id
-    `
-	var id int64
-	err := db.QueryRowContext(ctx, query, userID, productID).Scan(&id)
+// AddProductLike records that the user likes a product. Liking a product that
+// is already liked is a no-op; the returned state tells whether anything
+// changed. It returns ErrProductNotFound if the product does not exist.
+func AddProductLike(ctx context.Context, db *sql.DB, userID, productID uint64) (LikeState, error) {
+	return changeLike(ctx, db, int64(userID), int64(productID), true)
+}
+
+// changeLike likes or unlikes a product and keeps its like count in step, in one transaction.
+func changeLike(ctx context.Context, db *sql.DB, userID, productID int64, like bool) (LikeState, error) {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return LikeState{}, err
+	}
+	defer tx.Rollback()
+
+	state, err := New(db).WithTx(tx).setLike(ctx, userID, productID, like)
+	if err != nil {
+		return LikeState{}, err
+	}
+
+	return state, tx.Commit()
+}
+
+// setLike likes or unlikes a product, adjusts its like count and adds the
+// event to the outbox. It must run in a transaction so the like, the count and
+// the event cannot disagree.
+func (q *Queries) setLike(ctx context.Context, userID, productID int64, like bool) (LikeState, error) {
+	var err error
+	state := LikeState{ProductID: productID, Liked: like}
+	if like {
+		state.Changed, err = q.InsertLike(ctx, userID, productID)
+	} else {
+		state.Changed, err = q.DeleteLike(ctx, userID, productID)
+	}
+	if err != nil {
+		return LikeState{}, err
+	}
+
+	switch {
+	case state.Changed && like:
+		state.LikeCount, err = q.AdjustLikeCount(ctx, productID, 1)
+	case state.Changed:
+		state.LikeCount, err = q.AdjustLikeCount(ctx, productID, -1)
+	default:
+		state.LikeCount, err = q.GetLikeCount(ctx, productID)
+	}
+	if err != nil {
+		return LikeState{}, err
+	}
+
+	if state.Changed {
+		eventType := models.EventProductUnliked
+		if like {
+			eventType = models.EventProductLiked
+		}
+		event := models.LikeEvent{UserID: userID, ProductID: productID, LikeCount: state.LikeCount}
+		if err := q.insertEvent(ctx, eventType, productID, event); err != nil {
+			return LikeState{}, err
+		}
+		if err := q.insertLikeEvent(ctx, userID, productID, like); err != nil {
+			return LikeState{}, err
+		}
 	}
-	return id, nil
+	return state, nil
 }
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RefreshToken is a row of the refresh_tokens table.
+type RefreshToken struct {
+	ID         int64
+	UserID     int64
+	FamilyID   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	ReplacedBy sql.NullInt64
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
+
+// InsertRefreshToken stores a refresh token and fills in its ID.
+func InsertRefreshToken(ctx context.Context, db DBTX, token *RefreshToken) error {
+	query := `
+        INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `
+	return db.QueryRowContext(ctx, query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetRefreshTokenForUpdate retrieves a refresh token by hash and locks its row
+// until the surrounding transaction ends. It returns sql.ErrNoRows if no token matches.
+func GetRefreshTokenForUpdate(ctx context.Context, tx *sql.Tx, tokenHash string) (*RefreshToken, error) {
+	query := `
+        SELECT id, user_id, family_id, token_hash, expires_at, replaced_by, revoked_at, created_at
+        FROM refresh_tokens
+        WHERE token_hash = $1
+        FOR UPDATE
+    `
+	var token RefreshToken
+	err := tx.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash,
+		&token.ExpiresAt, &token.ReplacedBy, &token.RevokedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenReplaced records that a refresh token was rotated into replacedBy.
+func MarkRefreshTokenReplaced(ctx context.Context, db DBTX, id, replacedBy int64) error {
+	query := `
+        UPDATE refresh_tokens
+        SET replaced_by = $2
+        WHERE id = $1
+    `
+	_, err := db.ExecContext(ctx, query, id, replacedBy)
+	return err
+}
+
+// RevokeRefreshTokenFamily revokes every not yet revoked token in a family.
+func RevokeRefreshTokenFamily(ctx context.Context, db DBTX, familyID string) (int64, error) {
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW()
+        WHERE family_id = $1 AND revoked_at IS NULL
+    `
+	result, err := db.ExecContext(ctx, query, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"product-like/models"
+)
+
+var (
+	// ErrSlugTaken is returned when another shop already uses the slug.
+	ErrSlugTaken = errors.New("shop slug already taken")
+	// ErrShopHasProducts is returned when deleting a shop that still has products.
+	ErrShopHasProducts = errors.New("shop still has products")
+	// ErrOwnerNotFound is returned when creating a shop for a user that does not exist.
+	ErrOwnerNotFound = errors.New("shop owner not found")
+)
+
+const shopColumns = `id, name, slug, logo_url, status, owner_id, created_at, updated_at`
+
+// CreateShop inserts a new shop and fills in its ID and timestamps. The owner,
+// if any, becomes staff of the shop, and is promoted to the shop staff role if
+// they were a shopper. It returns ErrOwnerNotFound if the owner does not exist.
+func CreateShop(ctx context.Context, db *sql.DB, shop *models.Shop) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO shops (name, slug, logo_url, status, owner_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at, updated_at
+    `
+	err = tx.QueryRowContext(ctx, query,
+		shop.Name, shop.Slug, nullString(shop.LogoURL), shop.Status, shop.OwnerID,
+	).Scan(&shop.ID, &shop.CreatedAt, &shop.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrSlugTaken
+		}
+		if isForeignKeyViolation(err) {
+			return ErrOwnerNotFound
+		}
+		return err
+	}
+
+	if shop.OwnerID != nil {
+		staffQuery := `
+            INSERT INTO shop_staff (user_id, shop_id)
+            VALUES ($1, $2)
+            ON CONFLICT DO NOTHING
+        `
+		if _, err := tx.ExecContext(ctx, staffQuery, *shop.OwnerID, shop.ID); err != nil {
+			return err
+		}
+
+		// Admins keep their role, they can manage every shop anyway
+		roleQuery := `
+            UPDATE users
+            SET role = $2, updated_at = NOW()
+            WHERE id = $1 AND role = $3
+        `
+		if _, err := tx.ExecContext(ctx, roleQuery, *shop.OwnerID, models.RoleShopStaff, models.RoleShopper); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetShop retrieves a shop by ID. It returns sql.ErrNoRows if the shop does not exist.
+func GetShop(ctx context.Context, db *sql.DB, shopID int64) (*models.Shop, error) {
+	query := `
+        SELECT ` + shopColumns + `
+        FROM shops
+        WHERE id = $1
+    `
+	return scanShop(db.QueryRowContext(ctx, query, shopID))
+}
+
+// ListShops retrieves a page of shops ordered by ID, optionally filtered by
+// status, and the total number of matching shops.
+func ListShops(ctx context.Context, db *sql.DB, status string, page, limit int) ([]models.Shop, int, error) {
+	query := `
+        SELECT ` + shopColumns + `
+        FROM shops
+        WHERE $1 = '' OR status = $1
+        ORDER BY id
+        LIMIT $2
+        OFFSET ($3 - 1) * $2
+    `
+	rows, err := db.QueryContext(ctx, query, status, limit, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	shops := []models.Shop{}
+	for rows.Next() {
+		shop, err := scanShop(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		shops = append(shops, *shop)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var totalCount int
+	countQuery := `
+        SELECT COUNT(*)
+        FROM shops
+        WHERE $1 = '' OR status = $1
+    `
+	if err := db.QueryRowContext(ctx, countQuery, status).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	return shops, totalCount, nil
+}
+
+// UpdateShop updates a shop's name, slug, logo and status and refreshes the
+// timestamps. It returns sql.ErrNoRows if the shop does not exist.
+func UpdateShop(ctx context.Context, db *sql.DB, shop *models.Shop) error {
+	query := `
+        UPDATE shops
+        SET name = $2, slug = $3, logo_url = $4, status = $5, updated_at = NOW()
+        WHERE id = $1
+        RETURNING owner_id, created_at, updated_at
+    `
+	err := db.QueryRowContext(ctx, query,
+		shop.ID, shop.Name, shop.Slug, nullString(shop.LogoURL), shop.Status,
+	).Scan(&shop.OwnerID, &shop.CreatedAt, &shop.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrSlugTaken
+	}
+	return err
+}
+
+// DeleteShop deletes a shop. Shops that still have products cannot be
+// deleted; deleted products are detached from the shop instead.
+func DeleteShop(ctx context.Context, db *sql.DB, shopID int64) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the shop so no product can be added to it until it is gone
+	lockQuery := `
+        SELECT id
+        FROM shops
+        WHERE id = $1
+        FOR UPDATE
+    `
+	var id int64
+	if err := tx.QueryRowContext(ctx, lockQuery, shopID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var hasProducts bool
+	productsQuery := `
+        SELECT EXISTS (
+            SELECT 1
+            FROM products
+            WHERE shop_id = $1 AND deleted_at IS NULL
+        )
+    `
+	if err := tx.QueryRowContext(ctx, productsQuery, shopID).Scan(&hasProducts); err != nil {
+		return 0, err
+	}
+	if hasProducts {
+		return 0, ErrShopHasProducts
+	}
+
+	detachQuery := `
+        UPDATE products
+        SET shop_id = NULL
+        WHERE shop_id = $1
+    `
+	if _, err := tx.ExecContext(ctx, detachQuery, shopID); err != nil {
+		return 0, err
+	}
+
+	query := `
+        DELETE FROM shops
+        WHERE id = $1
+    `
+	result, err := tx.ExecContext(ctx, query, shopID)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAffected, tx.Commit()
+}
+
+func scanShop(row scanner) (*models.Shop, error) {
+	var (
+		shop    models.Shop
+		logoURL sql.NullString
+		ownerID sql.NullInt64
+	)
+	if err := row.Scan(
+		&shop.ID, &shop.Name, &shop.Slug, &logoURL, &shop.Status,
+		&ownerID, &shop.CreatedAt, &shop.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	shop.LogoURL = logoURL.String
+	if ownerID.Valid {
+		shop.OwnerID = &ownerID.Int64
+	}
+	return &shop, nil
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"product-like/models"
+)
+
+// recommendable is the condition products must meet to be recommended to
+// user $1: active, still on sale and not liked by the user already. Draft and
+// inactive products are never shown to shoppers.
+const recommendable = `p.deleted_at IS NULL AND p.status = '` + models.ProductStatusActive + `' AND p.is_purchasable
+        AND NOT EXISTS (SELECT 1 FROM favorites mine WHERE mine.user_id = $1 AND mine.product_id = p.id)`
+
+// RefreshProductSimilarities rebuilds product_similarities from favorites.
+// Two products are similar when the same users like them; their score is the
+// cosine similarity of their likers, co_likes / sqrt(likes_a * likes_b). Pairs
+// liked together by fewer than minCoLikes users are dropped, and only the
+// perProduct most similar products are kept for each product. Likes of deleted
+// products are ignored. It returns the number of stored pairs.
+func RefreshProductSimilarities(ctx context.Context, db *sql.DB, minCoLikes, perProduct int) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Readers keep seeing the previous similarities until the commit
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE product_similarities IN EXCLUSIVE MODE`); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_similarities`); err != nil {
+		return 0, err
+	}
+
+	query := `
+        WITH live_favorites AS (
+            SELECT f.user_id, f.product_id
+            FROM favorites f
+            INNER JOIN products p ON p.id = f.product_id
+            WHERE p.deleted_at IS NULL
+        ), likes AS (
+            SELECT product_id, COUNT(*) AS likes
+            FROM live_favorites
+            GROUP BY product_id
+        ), pairs AS (
+            SELECT a.product_id, b.product_id AS similar_product_id, COUNT(*) AS co_likes
+            FROM live_favorites a
+            INNER JOIN live_favorites b ON b.user_id = a.user_id AND b.product_id <> a.product_id
+            GROUP BY a.product_id, b.product_id
+            HAVING COUNT(*) >= $1
+        ), scored AS (
+            SELECT pairs.product_id, pairs.similar_product_id, pairs.co_likes,
+                   pairs.co_likes / SQRT(a.likes::DOUBLE PRECISION * b.likes) AS score
+            FROM pairs
+            INNER JOIN likes a ON a.product_id = pairs.product_id
+            INNER JOIN likes b ON b.product_id = pairs.similar_product_id
+        ), ranked AS (
+            SELECT scored.*,
+                   ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY score DESC, similar_product_id) AS rank
+            FROM scored
+        )
+        INSERT INTO product_similarities (product_id, similar_product_id, score, co_likes, computed_at)
+        SELECT product_id, similar_product_id, score, co_likes, NOW()
+        FROM ranked
+        WHERE rank <= $2
+    `
+	result, err := tx.ExecContext(ctx, query, minCoLikes, perProduct)
+	if err != nil {
+		return 0, err
+	}
+	stored, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return stored, tx.Commit()
+}
+
+// ListAlsoLikedProducts retrieves up to limit of the products most similar to
+// a product, most similar first. Products that are not active or cannot be
+// bought, and those the user already likes, are left out; userID is 0 for
+// anonymous callers.
+func ListAlsoLikedProducts(ctx context.Context, db *sql.DB, productID, userID int64, limit int) ([]models.RecommendedProduct, error) {
+	query := `
+        SELECT ` + productColumns + `, s.score
+        FROM product_similarities s
+        INNER JOIN products p ON p.id = s.similar_product_id
+        WHERE s.product_id = $2 AND ` + recommendable + `
+        ORDER BY s.score DESC, p.id DESC
+        LIMIT $3
+    `
+	return listRecommendedProducts(ctx, db, query, userID, productID, limit)
+}
+
+// ListRecommendedProducts retrieves up to limit products for a user, ranked
+// by the sum of their similarities to the products the user likes. Products
+// that are not active, cannot be bought or that the user already likes are
+// left out. The list is empty for users without likes, or whose likes have no
+// similar products.
+func ListRecommendedProducts(ctx context.Context, db *sql.DB, userID int64, limit int) ([]models.RecommendedProduct, error) {
+	query := `
+        SELECT ` + productColumns + `, r.score
+        FROM (
+            SELECT s.similar_product_id AS product_id, SUM(s.score) AS score
+            FROM favorites f
+            INNER JOIN product_similarities s ON s.product_id = f.product_id
+            WHERE f.user_id = $1
+            GROUP BY s.similar_product_id
+        ) r
+        INNER JOIN products p ON p.id = r.product_id
+        WHERE ` + recommendable + `
+        ORDER BY r.score DESC, p.id DESC
+        LIMIT $2
+    `
+	return listRecommendedProducts(ctx, db, query, userID, limit)
+}
+
+// ListTrendingRecommendations retrieves up to limit trending products for a
+// user, ranked by their trending score, for users ListRecommendedProducts
+// knows too little about. Products that are not active, cannot be bought or
+// that the user already likes are left out.
+func ListTrendingRecommendations(ctx context.Context, db *sql.DB, userID int64, limit int) ([]models.RecommendedProduct, error) {
+	query := `
+        SELECT ` + productColumns + `, r.score
+        FROM product_rankings r
+        INNER JOIN products p ON p.id = r.product_id
+        WHERE ` + recommendable + `
+        ORDER BY r.score DESC, p.id DESC
+        LIMIT $2
+    `
+	return listRecommendedProducts(ctx, db, query, userID, limit)
+}
+
+// listRecommendedProducts runs a query selecting productColumns followed by a score.
+func listRecommendedProducts(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.RecommendedProduct, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.RecommendedProduct{}
+	for rows.Next() {
+		var recommended models.RecommendedProduct
+		recommended.Product, err = scanProduct(rows, &recommended.Score)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, recommended)
+	}
+	return products, rows.Err()
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"product-like/models"
+)
+
+// RefreshTrendingRankings rebuilds product_rankings from the likes of the last
+// window. Every like scores 1 when it is made and half as much every halfLife
+// after that. Likes of deleted products are left out. The rankings are
+// replaced in one transaction, so readers never see a partial ranking. It
+// returns the number of ranked products.
+func RefreshTrendingRankings(ctx context.Context, db *sql.DB, window, halfLife time.Duration) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Readers keep seeing the previous rankings; concurrent refreshes, e.g.
+	// from several instances, take turns
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE product_rankings IN EXCLUSIVE MODE`); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_rankings`); err != nil {
+		return 0, err
+	}
+
+	query := `
+        INSERT INTO product_rankings (product_id, score, recent_likes, computed_at)
+        SELECT f.product_id,
+               SUM(POWER(0.5, EXTRACT(EPOCH FROM NOW() - f.created_at) / $2::DOUBLE PRECISION)),
+               COUNT(*),
+               NOW()
+        FROM favorites f
+        INNER JOIN products p ON p.id = f.product_id
+        WHERE f.created_at >= NOW() - $1::DOUBLE PRECISION * INTERVAL '1 second' AND p.deleted_at IS NULL
+        GROUP BY f.product_id
+    `
+	result, err := tx.ExecContext(ctx, query, window.Seconds(), halfLife.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	ranked, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return ranked, tx.Commit()
+}
+
+// ListTrendingProducts retrieves a page of the ranked products matching
+// filter, highest score first, and the total number of matching ranked
+// products. Products deleted since the last refresh are left out.
+func ListTrendingProducts(ctx context.Context, db *sql.DB, filter ProductFilter, page, limit int) ([]models.TrendingProduct, int, error) {
+	where, args := filter.where()
+
+	query := `
+        SELECT ` + productColumns + `, r.score, r.recent_likes, r.computed_at
+        FROM product_rankings r
+        INNER JOIN products p ON p.id = r.product_id
+        ` + where + `
+        ORDER BY r.score DESC, r.product_id DESC` + fmt.Sprintf(`
+        LIMIT $%d
+        OFFSET $%d`, len(args)+1, len(args)+2)
+	rows, err := db.QueryContext(ctx, query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []models.TrendingProduct{}
+	for rows.Next() {
+		var trending models.TrendingProduct
+		trending.Product, err = scanProduct(rows, &trending.TrendingScore, &trending.RecentLikes, &trending.RankedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		products = append(products, trending)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var totalCount int
+	countQuery := `
+        SELECT COUNT(*)
+        FROM product_rankings r
+        INNER JOIN products p ON p.id = r.product_id
+        ` + where
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	return products, totalCount, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"product-like/models"
+)
+
+// ErrEmailTaken is returned when a user with the same email already exists.
+var ErrEmailTaken = errors.New("email already registered")
+
+// CreateUser inserts a new user and fills in the generated ID and timestamps.
+func CreateUser(ctx context.Context, db *sql.DB, user *models.User) error {
+	query := `
+        INSERT INTO users (name, email, password, phone, status, role)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at, updated_at
+    `
+	err := db.QueryRowContext(ctx, query,
+		nullString(user.Name), user.Email, user.Password, nullString(user.Phone), user.Status, user.Role,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// GetUserByEmail retrieves a user by email. It returns sql.ErrNoRows if no user matches.
+func GetUserByEmail(ctx context.Context, db *sql.DB, email string) (*models.User, error) {
+	query := `
+        SELECT id, name, email, password, phone, status, role, created_at, updated_at, deleted_at
+        FROM users
+        WHERE email = $1
+    `
+	return scanUser(db.QueryRowContext(ctx, query, email))
+}
+
+// GetUserByID retrieves a user by ID, including soft-deleted users.
+// It returns sql.ErrNoRows if no user matches.
+func GetUserByID(ctx context.Context, db *sql.DB, userID int64) (*models.User, error) {
+	query := `
+        SELECT id, name, email, password, phone, status, role, created_at, updated_at, deleted_at
+        FROM users
+        WHERE id = $1
+    `
+	return scanUser(db.QueryRowContext(ctx, query, userID))
+}
+
+// GetUserShopIDs retrieves the IDs of the shops a user is staff of.
+func GetUserShopIDs(ctx context.Context, db *sql.DB, userID int64) ([]int64, error) {
+	query := `
+        SELECT shop_id
+        FROM shop_staff
+        WHERE user_id = $1
+        ORDER BY shop_id
+    `
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shopIDs []int64
+	for rows.Next() {
+		var shopID int64
+		if err := rows.Scan(&shopID); err != nil {
+			return nil, err
+		}
+		shopIDs = append(shopIDs, shopID)
+	}
+	return shopIDs, rows.Err()
+}
+
+func scanUser(row *sql.Row) (*models.User, error) {
+	var (
+		user      models.User
+		name      sql.NullString
+		phone     sql.NullString
+		deletedAt sql.NullTime
+	)
+	if err := row.Scan(
+		&user.ID, &name, &user.Email, &user.Password, &phone,
+		&user.Status, &user.Role, &user.CreatedAt, &user.UpdatedAt, &deletedAt,
+	); err != nil {
+		return nil, err
+	}
+	user.Name = name.String
+	user.Phone = phone.String
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+	return &user, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,268 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"product-like/models"
+
+	"github.com/lib/pq"
+)
+
+const webhookSubscriptionColumns = `id, shop_id, url, event_types, active, created_at, updated_at`
+
+const webhookDeliveryColumns = `d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+    d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+// WebhookDeliveryTarget is a claimed delivery along with where to send it.
+type WebhookDeliveryTarget struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// CreateWebhookSubscription inserts a webhook subscription and fills in its ID and timestamps.
+func CreateWebhookSubscription(ctx context.Context, db *sql.DB, subscription *models.WebhookSubscription) error {
+	query := `
+        INSERT INTO webhook_subscriptions (shop_id, url, event_types, secret, active)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at, updated_at
+    `
+	return db.QueryRowContext(ctx, query,
+		subscription.ShopID, subscription.URL, pq.Array(subscription.EventTypes), subscription.Secret, subscription.Active,
+	).Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt)
+}
+
+// ListWebhookSubscriptions retrieves a shop's webhook subscriptions, without their secrets.
+func ListWebhookSubscriptions(ctx context.Context, db *sql.DB, shopID int64) ([]models.WebhookSubscription, error) {
+	query := `
+        SELECT ` + webhookSubscriptionColumns + `
+        FROM webhook_subscriptions
+        WHERE shop_id = $1
+        ORDER BY id
+    `
+	rows, err := db.QueryContext(ctx, query, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// GetWebhookSubscription retrieves one of a shop's webhook subscriptions,
+// without its secret. It returns sql.ErrNoRows if the shop has no such subscription.
+func GetWebhookSubscription(ctx context.Context, db *sql.DB, shopID, subscriptionID int64) (models.WebhookSubscription, error) {
+	query := `
+        SELECT ` + webhookSubscriptionColumns + `
+        FROM webhook_subscriptions
+        WHERE id = $1 AND shop_id = $2
+    `
+	return scanWebhookSubscription(db.QueryRowContext(ctx, query, subscriptionID, shopID))
+}
+
+// DeleteWebhookSubscription deletes one of a shop's webhook subscriptions along with its deliveries.
+func DeleteWebhookSubscription(ctx context.Context, db *sql.DB, shopID, subscriptionID int64) (int64, error) {
+	query := `
+        DELETE FROM webhook_subscriptions
+        WHERE id = $1 AND shop_id = $2
+    `
+	result, err := db.ExecContext(ctx, query, subscriptionID, shopID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// EnqueueWebhookDeliveries queues body for delivery to the active
+// subscriptions of the event's product's shop that want the event type. An
+// event is only queued once per subscription, however often it is enqueued.
+// It returns the number of deliveries queued.
+func EnqueueWebhookDeliveries(ctx context.Context, db *sql.DB, event models.Event, body []byte) (int64, error) {
+	query := `
+        INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+        SELECT s.id, $1, $2, $3::JSONB
+        FROM webhook_subscriptions s
+        INNER JOIN products p ON p.shop_id = s.shop_id
+        WHERE p.id = $4 AND s.active AND $2 = ANY(s.event_types)
+        ON CONFLICT (subscription_id, event_id) DO NOTHING
+    `
+	result, err := db.ExecContext(ctx, query, event.ID, event.Type, string(body), event.ProductID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ClaimDueWebhookDeliveries claims up to limit pending deliveries that are
+// due, oldest first, by leasing them: their next attempt is pushed back by
+// lease, so no other worker picks them up while they are being sent. The
+// claim is committed right away, so no transaction or row lock is held while
+// sending; the outcome of each delivery is recorded with MarkWebhookDelivered
+// or MarkWebhookFailed. A delivery whose worker dies is retried once the
+// lease runs out.
+func ClaimDueWebhookDeliveries(ctx context.Context, db DBTX, limit int, lease time.Duration) ([]WebhookDeliveryTarget, error) {
+	query := `
+        WITH due AS (
+            SELECT id
+            FROM webhook_deliveries
+            WHERE status = $1 AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at, id
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        )
+        UPDATE webhook_deliveries AS d
+        SET next_attempt_at = NOW() + $3::DOUBLE PRECISION * INTERVAL '1 second'
+        FROM due, webhook_subscriptions s
+        WHERE d.id = due.id AND s.id = d.subscription_id
+        RETURNING ` + webhookDeliveryColumns + `, s.url, s.secret
+    `
+	rows, err := db.QueryContext(ctx, query, models.WebhookDeliveryPending, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []WebhookDeliveryTarget{}
+	for rows.Next() {
+		var target WebhookDeliveryTarget
+		target.WebhookDelivery, err = scanWebhookDelivery(rows, &target.URL, &target.Secret)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+// MarkWebhookDelivered records that the receiver accepted a delivery.
+func MarkWebhookDelivered(ctx context.Context, db DBTX, deliveryID int64, statusCode int) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = NULL, delivered_at = NOW()
+        WHERE id = $1
+    `
+	_, err := db.ExecContext(ctx, query, deliveryID, models.WebhookDeliveryDelivered, statusCode)
+	return err
+}
+
+// MarkWebhookFailed records a failed delivery attempt. The delivery is retried
+// at retryAt, unless dead is set, which moves it to the dead-letter state.
+// statusCode is 0 if the receiver could not be reached.
+func MarkWebhookFailed(ctx context.Context, db DBTX, deliveryID int64, statusCode int, reason string, retryAt time.Time, dead bool) error {
+	status := models.WebhookDeliveryPending
+	if dead {
+		status = models.WebhookDeliveryDead
+	}
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = $4, next_attempt_at = $5
+        WHERE id = $1
+    `
+	code := sql.NullInt64{Int64: int64(statusCode), Valid: statusCode != 0}
+	_, err := db.ExecContext(ctx, query, deliveryID, status, code, reason, retryAt)
+	return err
+}
+
+// ListWebhookDeliveries retrieves a page of a subscription's deliveries,
+// newest first, optionally only those with the given status, and their total number.
+func ListWebhookDeliveries(ctx context.Context, db *sql.DB, subscriptionID int64, status string, page, limit int) ([]models.WebhookDelivery, int, error) {
+	where := `WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2)`
+	query := `
+        SELECT ` + webhookDeliveryColumns + `
+        FROM webhook_deliveries d
+        ` + where + `
+        ORDER BY d.created_at DESC, d.id DESC
+        LIMIT $3
+        OFFSET ($4 - 1) * $3
+    `
+	rows, err := db.QueryContext(ctx, query, subscriptionID, status, limit, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var totalCount int
+	countQuery := `
+        SELECT COUNT(*)
+        FROM webhook_deliveries d
+        ` + where
+	if err := db.QueryRowContext(ctx, countQuery, subscriptionID, status).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, totalCount, nil
+}
+
+// RedeliverWebhook queues a delivery of a subscription to be sent again right
+// away, with a fresh set of attempts, whatever its status. It returns
+// sql.ErrNoRows if the subscription has no such delivery.
+func RedeliverWebhook(ctx context.Context, db *sql.DB, subscriptionID, deliveryID int64) (models.WebhookDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries AS d
+        SET status = $3, attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+        WHERE d.id = $1 AND d.subscription_id = $2
+        RETURNING ` + webhookDeliveryColumns
+	return scanWebhookDelivery(db.QueryRowContext(ctx, query, deliveryID, subscriptionID, models.WebhookDeliveryPending))
+}
+
+func scanWebhookSubscription(row scanner) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := row.Scan(
+		&subscription.ID, &subscription.ShopID, &subscription.URL, pq.Array(&subscription.EventTypes),
+		&subscription.Active, &subscription.CreatedAt, &subscription.UpdatedAt,
+	); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	return subscription, nil
+}
+
+// scanWebhookDelivery scans webhookDeliveryColumns followed by the extra destinations.
+func scanWebhookDelivery(row scanner, extra ...interface{}) (models.WebhookDelivery, error) {
+	var (
+		delivery       models.WebhookDelivery
+		payload        []byte
+		lastStatusCode sql.NullInt64
+		lastError      sql.NullString
+		deliveredAt    sql.NullTime
+	)
+	dest := []interface{}{
+		&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &payload,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &lastStatusCode, &lastError,
+		&deliveredAt, &delivery.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery.Payload = payload
+	if lastStatusCode.Valid {
+		code := int(lastStatusCode.Int64)
+		delivery.LastStatusCode = &code
+	}
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, nil
+}
@@ -1,23 +1,31 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"strconv"
+	"os"
 	"time"
 
 	"product-like/api"
+	"product-like/models"
 	"product-like/pkg/auth"
+	"product-like/pkg/config"
+	"product-like/pkg/cursor"
+	"product-like/pkg/live"
+	"product-like/pkg/notify"
+	"product-like/pkg/outbox"
+	"product-like/pkg/trending"
+	"product-like/pkg/webhook"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
 )
 
 func main() {
 	gin.SetMode(gin.DebugMode)
-	dbConn, err := sql.Open("postgres", "postgres://postgres:postgrespass@db:5432/product_like_db?sslmode=disable")
+	dbConn, err := sql.Open("postgres", config.DatabaseURL())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -34,31 +42,121 @@ func main() {
 	// Create a new router
 	router := gin.Default()
 
-	authMiddleware, err := auth.NewMiddleware("my-secret-key")
+	jwtSecret := config.Get("JWT_SECRET", "my-secret-key")
+	authOptions := []auth.Option{
+		auth.WithUserLoader(auth.DBUserLoader(dbConn)),
+		auth.WithUserCache(30 * time.Second),
+	}
+
+	keySet, err := loadKeySet(jwtSecret)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	if keySet != nil {
+		authOptions = append(authOptions, auth.WithKeySet(keySet))
+	}
+
+	authMiddleware, err := auth.NewMiddleware(jwtSecret, authOptions...)
 	if err != nil {
 		log.Fatalf("Failed to initialize auth middleware: %v", err)
 	}
 
-	// Initialize API routes
-	apiRoutes := router.Group("/api")
+	refreshTokens := auth.NewRefreshTokens(dbConn, auth.RefreshTokenTTL)
+	cursors := cursor.NewCodec([]byte(config.Get("CURSOR_SECRET", jwtSecret)))
+
+	// Live product updates; with LIVE_UPDATES_LISTEN=true they are exchanged
+	// with other instances through Postgres LISTEN/NOTIFY
+	updates := live.NewHub()
+	if config.Get("LIVE_UPDATES_LISTEN", "false") == "true" {
+		if err := updates.ListenPostgres(context.Background(), dbConn, config.DatabaseURL()); err != nil {
+			log.Fatalf("Failed to listen for live updates: %v", err)
+		}
+	}
+
+	// Deliver notifications, such as price drop alerts, in the background
+	go notify.NewDispatcher(dbConn, newNotifier(), 5*time.Second, 100).Run(context.Background())
 
-	apiRoutes.POST("/like-product", authMiddleware.Authorize(), api.LikeProduct(dbConn))
-	apiRoutes.GET("/liked-products", authMiddleware.Authorize(), api.RetrieveLikedProducts(dbConn))
-	apiRoutes.POST("/cancel-like", authMiddleware.Authorize(), api.CancelProductLike(dbConn))
+	// Publish like and product events from the outbox to downstream consumers
+	publisher := outbox.MultiPublisher(newPublisher(), webhook.NewFanout(dbConn))
+	go outbox.NewRelay(dbConn, publisher, time.Second, 100).Run(context.Background())
 
-	apiRoutes.GET("/products", api.GetProducts(dbConn))
-	apiRoutes.POST("/products", authMiddleware.Authorize(), api.CreateProduct(dbConn))
-	apiRoutes.PUT("/products/:id", authMiddleware.Authorize(), api.UpdateProduct(dbConn))
-	apiRoutes.DELETE("/products/:id", authMiddleware.Authorize(), api.DeleteProduct(dbConn))
+	// Send queued shop webhooks
+	go webhook.NewDeliverer(dbConn, webhook.NewClient(10*time.Second), 5*time.Second, 50).Run(context.Background())
 
-	// Example: Generate a JWT token for user with ID 1234
-	userID := int64(1234)
-	token, err := GenerateToken(userID)
+	// Rank trending products from recent likes in the background
+	trendingRefresher, err := newTrendingRefresher(dbConn)
 	if err != nil {
-		log.Fatal("Failed to generate token:", err)
+		log.Fatalf("Failed to configure trending products: %v", err)
 	}
+	go trendingRefresher.Run(context.Background())
+
+	// Public keys for services verifying our tokens
+	router.GET("/.well-known/jwks.json", authMiddleware.JWKS())
+
+	// Initialize API routes
+	apiRoutes := router.Group("/api")
+
+	apiRoutes.POST("/auth/register", api.Register(dbConn, authMiddleware, refreshTokens))
+	apiRoutes.POST("/auth/login", api.Login(dbConn, authMiddleware, refreshTokens))
+	apiRoutes.POST("/auth/refresh", api.RefreshToken(dbConn, authMiddleware, refreshTokens))
+	apiRoutes.POST("/auth/logout", api.Logout(refreshTokens))
+
+	apiRoutes.POST("/like-product", authMiddleware.Authorize(), api.LikeProduct(dbConn, updates))
+	apiRoutes.GET("/liked-products", authMiddleware.Authorize(), api.RetrieveLikedProducts(dbConn, cursors))
+	apiRoutes.POST("/cancel-like", authMiddleware.Authorize(), api.CancelProductLike(dbConn, updates))
+	apiRoutes.PUT("/products/:id/like", authMiddleware.Authorize(), api.PutProductLike(dbConn, updates))
+	apiRoutes.DELETE("/products/:id/like", authMiddleware.Authorize(), api.DeleteProductLike(dbConn, updates))
+	apiRoutes.PUT("/products/:id/like/alerts", authMiddleware.Authorize(), api.PutAvailabilityAlerts(dbConn))
+	apiRoutes.DELETE("/products/:id/like/alerts", authMiddleware.Authorize(), api.DeleteAvailabilityAlerts(dbConn))
+	apiRoutes.POST("/likes/status", authMiddleware.Authorize(), api.GetLikeStatuses(dbConn))
+	apiRoutes.POST("/likes/batch", authMiddleware.Authorize(), api.BatchLikes(dbConn, updates))
+
+	apiRoutes.GET("/recommendations", authMiddleware.Authorize(), api.GetRecommendations(dbConn))
+
+	apiRoutes.GET("/notifications", authMiddleware.Authorize(), api.GetNotifications(dbConn))
+	apiRoutes.GET("/notifications/settings", authMiddleware.Authorize(), api.GetNotificationSettings(dbConn))
+	apiRoutes.PUT("/notifications/settings", authMiddleware.Authorize(), api.UpdateNotificationSettings(dbConn))
+
+	apiRoutes.GET("/collections", authMiddleware.Authorize(), api.GetCollections(dbConn))
+	apiRoutes.POST("/collections", authMiddleware.Authorize(), api.CreateCollection(dbConn))
+	apiRoutes.PUT("/collections/order", authMiddleware.Authorize(), api.ReorderCollections(dbConn))
+	apiRoutes.PUT("/collections/:id", authMiddleware.Authorize(), api.RenameCollection(dbConn))
+	apiRoutes.DELETE("/collections/:id", authMiddleware.Authorize(), api.DeleteCollection(dbConn))
+	apiRoutes.GET("/collections/:id/products", authMiddleware.Authorize(), api.GetCollectionProducts(dbConn))
+	apiRoutes.PUT("/collections/:id/products/:product_id", authMiddleware.Authorize(), api.AddCollectionProduct(dbConn))
+	apiRoutes.DELETE("/collections/:id/products/:product_id", authMiddleware.Authorize(), api.RemoveCollectionProduct(dbConn))
+	apiRoutes.PUT("/collections/:id/share", authMiddleware.Authorize(), api.ShareCollection(dbConn))
+	apiRoutes.POST("/collections/:id/share/regenerate", authMiddleware.Authorize(), api.RegenerateCollectionShare(dbConn))
+	apiRoutes.DELETE("/collections/:id/share", authMiddleware.Authorize(), api.UnshareCollection(dbConn))
+	apiRoutes.GET("/public/wishlists/:slug", api.GetPublicWishlist(dbConn))
+
+	// Product management is limited to admins and the staff of the product's shop
+	requireProductManager := authMiddleware.Require(models.RoleAdmin, models.RoleShopStaff)
+
+	// A token is optional here; with one, include=liked_by_me marks the liked products
+	apiRoutes.GET("/products", authMiddleware.OptionalAuthorize(), api.GetProducts(dbConn, cursors))
+	apiRoutes.POST("/products", authMiddleware.Authorize(), requireProductManager, api.CreateProduct(dbConn))
+	apiRoutes.PUT("/products/:id", authMiddleware.Authorize(), requireProductManager, api.UpdateProduct(dbConn, updates))
+	apiRoutes.PATCH("/products/:id", authMiddleware.Authorize(), requireProductManager, api.PatchProduct(dbConn, updates))
+	apiRoutes.DELETE("/products/:id", authMiddleware.Authorize(), requireProductManager, api.DeleteProduct(dbConn, updates))
+	apiRoutes.GET("/products/trending", api.GetTrendingProducts(dbConn))
+	apiRoutes.GET("/products/:id/also-liked", authMiddleware.OptionalAuthorize(), api.GetAlsoLikedProducts(dbConn))
+	apiRoutes.GET("/products/:id/events", api.ProductEvents(dbConn, updates))
+
+	apiRoutes.GET("/shops", api.GetShops(dbConn))
+	apiRoutes.GET("/shops/:id", api.GetShop(dbConn))
+	apiRoutes.GET("/shops/:id/products", api.GetShopProducts(dbConn))
+	apiRoutes.POST("/shops", authMiddleware.Authorize(), authMiddleware.Require(models.RoleAdmin), api.CreateShop(dbConn, authMiddleware))
+	apiRoutes.PUT("/shops/:id", authMiddleware.Authorize(), requireProductManager, api.UpdateShop(dbConn))
+	apiRoutes.DELETE("/shops/:id", authMiddleware.Authorize(), authMiddleware.Require(models.RoleAdmin), api.DeleteShop(dbConn))
 
-	fmt.Printf("Generated token: %s\n", token)
+	apiRoutes.GET("/shops/:id/analytics/likes", authMiddleware.Authorize(), requireProductManager, api.GetShopLikeAnalytics(dbConn))
+
+	apiRoutes.GET("/shops/:id/webhooks", authMiddleware.Authorize(), requireProductManager, api.GetWebhooks(dbConn))
+	apiRoutes.POST("/shops/:id/webhooks", authMiddleware.Authorize(), requireProductManager, api.CreateWebhook(dbConn))
+	apiRoutes.DELETE("/shops/:id/webhooks/:webhook_id", authMiddleware.Authorize(), requireProductManager, api.DeleteWebhook(dbConn))
+	apiRoutes.GET("/shops/:id/webhooks/:webhook_id/deliveries", authMiddleware.Authorize(), requireProductManager, api.GetWebhookDeliveries(dbConn))
+	apiRoutes.POST("/shops/:id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", authMiddleware.Authorize(), requireProductManager, api.RedeliverWebhook(dbConn))
 
 	// Start the server on localhost:8080
 	err = router.Run(":8080")
@@ -68,23 +166,95 @@ func main() {
 
 }
 
-func GenerateToken(userID int64) (string, error) {
-	// expired in 1 hour
-	expirationTime := time.Now().Add(1 * time.Hour)
+// loadKeySet builds the JWT key set from the environment:
+//
+//	JWT_SIGNING_KEYS   comma separated kid=path PEM files; the first one signs new tokens
+//	JWT_RETIRED_KEYS   kid=path keys that are still accepted until JWT_RETIRED_UNTIL
+//	JWT_RETIRED_UNTIL  RFC 3339 time after which retired keys are rejected (default: rejected right away)
+//
+// It returns nil if no signing keys are configured, in which case tokens are
+// signed with the shared secret. Otherwise the shared secret is retired too, so
+// tokens issued before switching keep working until JWT_RETIRED_UNTIL. The
+// deadline is absolute so restarting the server does not extend it; set it to
+// the time of the rotation plus the access token TTL.
+func loadKeySet(jwtSecret string) (*auth.KeySet, error) {
+	signingKeys, err := auth.ParseKeySpec(os.Getenv("JWT_SIGNING_KEYS"))
+	if err != nil || len(signingKeys) == 0 {
+		return nil, err
+	}
+
+	retiredKeys, err := auth.ParseKeySpec(os.Getenv("JWT_RETIRED_KEYS"))
+	if err != nil {
+		return nil, err
+	}
 
-	// Create the JWT claims
-	claims := jwt.StandardClaims{
-		ExpiresAt: expirationTime.Unix(),
-		Issuer:    "product-like-issuer",
-		Subject:   strconv.FormatInt(userID, 10),
+	var retiredUntil time.Time
+	if value := os.Getenv("JWT_RETIRED_UNTIL"); value != "" {
+		if retiredUntil, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("invalid JWT_RETIRED_UNTIL: %w", err)
+		}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secretKey := []byte("my-secret-key")
-	tokenString, err := token.SignedString(secretKey)
+	keySet, err := auth.NewKeySet(signingKeys[0])
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	for _, key := range signingKeys[1:] {
+		keySet.Add(key)
 	}
 
-	return tokenString, nil
+	if jwtSecret != "" {
+		retiredKeys = append(retiredKeys, auth.NewHMACKey("", []byte(jwtSecret)))
+	}
+	for _, key := range retiredKeys {
+		keySet.Add(key)
+		if err := keySet.Retire(key.ID, retiredUntil); err != nil {
+			return nil, err
+		}
+	}
+
+	return keySet, nil
+}
+
+// newNotifier picks how notifications are delivered from the environment:
+//
+//	NOTIFIER     log (default) writes them to the log, file appends them to NOTIFY_FILE
+//	NOTIFY_FILE  the file for NOTIFIER=file (default: notifications.log)
+func newNotifier() notify.Notifier {
+	if config.Get("NOTIFIER", "log") == "file" {
+		return notify.NewFileNotifier(config.Get("NOTIFY_FILE", "notifications.log"))
+	}
+	return notify.LogNotifier{}
+}
+
+// newPublisher picks where outbox events are published from the environment:
+//
+//	EVENT_PUBLISHER  stdout (default) writes them to standard output, file appends them to EVENTS_FILE
+//	EVENTS_FILE      the file for EVENT_PUBLISHER=file (default: events.log)
+func newPublisher() outbox.Publisher {
+	if config.Get("EVENT_PUBLISHER", "stdout") == "file" {
+		return outbox.NewFilePublisher(config.Get("EVENTS_FILE", "events.log"))
+	}
+	return outbox.NewStdoutPublisher()
+}
+
+// newTrendingRefresher configures the trending products ranking from the environment:
+//
+//	TRENDING_WINDOW            how far back likes count towards the ranking (default: 48h)
+//	TRENDING_HALF_LIFE         how long it takes a like to lose half its weight (default: 12h)
+//	TRENDING_REFRESH_INTERVAL  how often the ranking is rebuilt (default: 5m)
+func newTrendingRefresher(dbConn *sql.DB) (*trending.Refresher, error) {
+	window, err := config.Duration("TRENDING_WINDOW", 48*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	halfLife, err := config.Duration("TRENDING_HALF_LIFE", 12*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := config.Duration("TRENDING_REFRESH_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return trending.NewRefresher(dbConn, window, halfLife, interval), nil
 }
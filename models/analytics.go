@@ -0,0 +1,59 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Time series intervals of the like analytics, as understood by date_trunc.
+const (
+	AnalyticsIntervalDay   = "day"
+	AnalyticsIntervalWeek  = "week"
+	AnalyticsIntervalMonth = "month"
+)
+
+// LikeStats counts the likes and unlikes of a period. LikesToUnlikes is nil
+// when there were no unlikes.
+type LikeStats struct {
+	Likes          int64    `json:"likes"`
+	Unlikes        int64    `json:"unlikes"`
+	UniqueLikers   int64    `json:"unique_likers"`
+	LikesToUnlikes *float64 `json:"likes_to_unlikes"`
+}
+
+// SetRatio works out LikesToUnlikes, rounded to two decimals.
+func (s *LikeStats) SetRatio() {
+	s.LikesToUnlikes = nil
+	if s.Unlikes > 0 {
+		ratio := math.Round(float64(s.Likes)/float64(s.Unlikes)*100) / 100
+		s.LikesToUnlikes = &ratio
+	}
+}
+
+// ProductLikeStats is a product's like activity over the analytics period.
+// LikeCount is the number of users liking it now, whatever the period.
+type ProductLikeStats struct {
+	ProductID int64  `json:"product_id"`
+	Name      string `json:"name"`
+	LikeCount int64  `json:"like_count"`
+	LikeStats
+}
+
+// LikeBucket is the like activity of a shop in one interval of the time
+// series, starting at Start (UTC).
+type LikeBucket struct {
+	Start   time.Time `json:"start"`
+	Likes   int64     `json:"likes"`
+	Unlikes int64     `json:"unlikes"`
+}
+
+// LikeAnalytics is the like activity of a shop's products from From until To.
+type LikeAnalytics struct {
+	ShopID   int64              `json:"shop_id"`
+	Interval string             `json:"interval"`
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Totals   LikeStats          `json:"totals"`
+	Products []ProductLikeStats `json:"products"`
+	Series   []LikeBucket       `json:"series"`
+}
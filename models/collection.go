@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// DefaultCollectionName is the name the default collection is created with.
+const DefaultCollectionName = "All likes"
+
+// Collection is a named folder of a user's liked products. The default
+// collection holds all of the user's likes.
+type Collection struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	Name         string `json:"name"`
+	IsDefault    bool   `json:"is_default"`
+	Position     int    `json:"position"`
+	ProductCount int    `json:"product_count"`
+	// ShareSlug is set while the collection is shared publicly.
+	ShareSlug string     `json:"share_slug,omitempty"`
+	SharedAt  *time.Time `json:"shared_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types stored in outbox.event_type.
+const (
+	EventProductLiked   = "product_liked"
+	EventProductUnliked = "product_unliked"
+	EventProductCreated = "product_created"
+	EventProductUpdated = "product_updated"
+	EventProductDeleted = "product_deleted"
+)
+
+// Event is a change published to downstream consumers through the outbox.
+// Consumers must tolerate duplicates: an event is published at least once.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	ProductID int64           `json:"product_id"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	Attempts  int             `json:"-"`
+}
+
+// LikeEvent is the payload of product_liked and product_unliked events.
+type LikeEvent struct {
+	UserID    int64 `json:"user_id"`
+	ProductID int64 `json:"product_id"`
+	LikeCount int64 `json:"like_count"`
+}
+
+// ProductEvent is the payload of product_created, product_updated and
+// product_deleted events; it carries the product as it is after the change.
+type ProductEvent struct {
+	Product Product `json:"product"`
+}
@@ -10,3 +10,62 @@ type Favorite struct {
 	ProductID int64     `json:"product_id"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Reasons a liked product can no longer be bought.
+const (
+	UnavailableDeleted        = "deleted"
+	UnavailableNotPurchasable = "not_purchasable"
+)
+
+// LikedProduct is a product as it appears in a user's liked products, along
+// with when it was liked. Products that were deleted or can no longer be
+// bought are kept in the list and flagged with UnavailableReason.
+type LikedProduct struct {
+	Product
+	FavoriteID        int64     `json:"favorite_id"`
+	LikedAt           time.Time `json:"liked_at"`
+	Available         bool      `json:"available"`
+	UnavailableReason string    `json:"unavailable_reason,omitempty"`
+}
+
+// NewLikedProduct creates a LikedProduct and works out whether it is still available.
+func NewLikedProduct(product Product, favoriteID int64, likedAt time.Time) LikedProduct {
+	reason := unavailableReason(product)
+	return LikedProduct{
+		Product:           product,
+		FavoriteID:        favoriteID,
+		LikedAt:           likedAt,
+		Available:         reason == "",
+		UnavailableReason: reason,
+	}
+}
+
+// SharedProduct is a product as it appears on a public wishlist. Unlike
+// LikedProduct it says nothing about the user who liked it.
+type SharedProduct struct {
+	Product
+	Available         bool   `json:"available"`
+	UnavailableReason string `json:"unavailable_reason,omitempty"`
+}
+
+// NewSharedProduct creates a SharedProduct and works out whether it is still available.
+func NewSharedProduct(product Product) SharedProduct {
+	reason := unavailableReason(product)
+	return SharedProduct{
+		Product:           product,
+		Available:         reason == "",
+		UnavailableReason: reason,
+	}
+}
+
+// unavailableReason tells why a product can no longer be bought, or returns
+// an empty string if it can.
+func unavailableReason(product Product) string {
+	switch {
+	case product.DeletedAt != nil:
+		return UnavailableDeleted
+	case !product.IsPurchasable:
+		return UnavailableNotPurchasable
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification types stored in notifications.type.
+const (
+	NotificationPriceDrop    = "price_drop"
+	NotificationBackInStock  = "back_in_stock"
+	NotificationPreorderOpen = "preorder_open"
+	NotificationPurchasable  = "purchasable"
+)
+
+// Notification delivery statuses, as filtered on by GET /notifications.
+const (
+	NotificationPending   = "pending"
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed"
+)
+
+// Notification tells a user about a change to a product they like.
+type Notification struct {
+	ID          int64           `json:"id"`
+	UserID      int64           `json:"user_id"`
+	ProductID   int64           `json:"product_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DeliveredAt *time.Time      `json:"delivered_at,omitempty"`
+	FailedAt    *time.Time      `json:"failed_at,omitempty"`
+	Attempts    int             `json:"-"`
+}
+
+// PriceDrop is the payload of a price_drop notification.
+type PriceDrop struct {
+	ProductName        string  `json:"product_name"`
+	OldDiscountedPrice int64   `json:"old_discounted_price"`
+	NewDiscountedPrice int64   `json:"new_discounted_price"`
+	OriginPrice        int64   `json:"origin_price"`
+	DiscountedRate     float64 `json:"discounted_rate"`
+}
+
+// AvailabilityChange is the payload of back_in_stock, preorder_open and
+// purchasable notifications.
+type AvailabilityChange struct {
+	ProductName     string `json:"product_name"`
+	InStock         bool   `json:"in_stock"`
+	IsPreorder      bool   `json:"is_preorder"`
+	IsPurchasable   bool   `json:"is_purchasable"`
+	DiscountedPrice int64  `json:"discounted_price"`
+}
+
+// NotificationSettings are a user's alert preferences.
+type NotificationSettings struct {
+	UserID           int64   `json:"user_id"`
+	PriceDropEnabled bool    `json:"price_drop_enabled"`
+	PriceDropMinRate float64 `json:"price_drop_min_rate"`
+}
+
+// DefaultNotificationSettings returns the settings of users who never changed them.
+func DefaultNotificationSettings(userID int64) NotificationSettings {
+	return NotificationSettings{UserID: userID, PriceDropEnabled: true}
+}
@@ -4,21 +4,32 @@ import (
 	"time"
 )
 
+// Product statuses stored in products.status.
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusInactive = "inactive"
+)
+
 type Product struct {
-	ID                uint64    `json:"id"`
-	ShopID            int64     `json:"shop_id"`
-	Name              string    `json:"name"`
-	Description       string    `json:"description"`
-	ThumbnailURL      string    `json:"thumbnail_url"`
-	OriginPrice       int64     `json:"origin_price"`
-	DiscountedPrice   int64     `json:"discounted_price"`
-	DiscountedRate    float64   `json:"discounted_rate"`
-	Status            string    `json:"status"`
-	InStock           bool      `json:"in_stock"`
-	IsPreorder        bool      `json:"is_preorder"`
-	IsPurchasable     bool      `json:"is_purchasable"`
-	DeliveryCondition string    `json:"delivery_condition"`
-	DeliveryDisplay   string    `json:"delivery_display"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                uint64     `json:"id"`
+	ShopID            int64      `json:"shop_id"`
+	Name              string     `json:"name"`
+	Description       string     `json:"description"`
+	ThumbnailURL      string     `json:"thumbnail_url"`
+	OriginPrice       int64      `json:"origin_price"`
+	DiscountedPrice   int64      `json:"discounted_price"`
+	DiscountedRate    float64    `json:"discounted_rate"`
+	Status            string     `json:"status"`
+	InStock           bool       `json:"in_stock"`
+	IsPreorder        bool       `json:"is_preorder"`
+	IsPurchasable     bool       `json:"is_purchasable"`
+	DeliveryCondition string     `json:"delivery_condition"`
+	DeliveryDisplay   string     `json:"delivery_display"`
+	LikeCount         int64      `json:"like_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
+	// LikedByMe is only set when the caller asked whether they liked the product.
+	LikedByMe *bool `json:"liked_by_me,omitempty"`
 }
@@ -0,0 +1,15 @@
+package models
+
+// Where recommendations come from.
+const (
+	RecommendationSourceSimilarLikes = "similar_likes"
+	RecommendationSourceTrending     = "trending"
+)
+
+// RecommendedProduct is a product recommended alongside another one or to a
+// user, with the score it was ranked by. Scores are only comparable within
+// one list.
+type RecommendedProduct struct {
+	Product
+	Score float64 `json:"score"`
+}
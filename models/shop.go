@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Shop statuses stored in shops.status.
+const (
+	ShopStatusActive   = "active"
+	ShopStatusInactive = "inactive"
+)
+
+type Shop struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	LogoURL   string    `json:"logo_url"`
+	Status    string    `json:"status"`
+	OwnerID   *int64    `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
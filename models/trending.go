@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// TrendingProduct is a product as it appears in the trending ranking.
+// TrendingScore sums the product's recent likes, each weighted down the older
+// it is, and RecentLikes counts them without the weighting.
+type TrendingProduct struct {
+	Product
+	TrendingScore float64   `json:"trending_score"`
+	RecentLikes   int64     `json:"recent_likes"`
+	RankedAt      time.Time `json:"ranked_at"`
+}
@@ -2,16 +2,31 @@ package models
 
 import "time"
 
+// User statuses stored in users.status.
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+)
+
+// User roles stored in users.role and carried in the "role" token claim.
+const (
+	RoleShopper   = "shopper"
+	RoleShopStaff = "shop_staff"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	Phone     string    `json:"phone"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	DeletedAt time.Time `json:"deleted_at"`
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Password  string     `json:"-"`
+	Phone     string     `json:"phone"`
+	Status    string     `json:"status"`
+	Role      string     `json:"role"`
+	ShopIDs   []int64    `json:"shop_ids,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // NewUser creates a new User instance.
@@ -22,8 +37,29 @@ func NewUser(name, email, password, phone, status string) *User {
 		Password:  password,
 		Phone:     phone,
 		Status:    status,
+		Role:      RoleShopper,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		DeletedAt: time.Time{}, // Assuming initially deleted_at is empty
 	}
 }
+
+// IsActive reports whether the user may use the API: active status and not soft-deleted.
+func (u *User) IsActive() bool {
+	return u.Status == UserStatusActive && u.DeletedAt == nil
+}
+
+// CanManageShop reports whether the user may manage products of the given shop.
+// Admins manage every shop; shop staff only the shops they belong to.
+func (u *User) CanManageShop(shopID int64) bool {
+	switch u.Role {
+	case RoleAdmin:
+		return true
+	case RoleShopStaff:
+		for _, id := range u.ShopIDs {
+			if id == shopID {
+				return true
+			}
+		}
+	}
+	return false
+}
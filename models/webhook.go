@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEventTypes are the event types shops can subscribe to.
+var WebhookEventTypes = []string{EventProductLiked, EventProductUnliked}
+
+// Webhook delivery statuses stored in webhook_deliveries.status.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookSubscription sends the shop's events of the given types to URL.
+type WebhookSubscription struct {
+	ID         int64    `json:"id"`
+	ShopID     int64    `json:"shop_id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is only shown when the subscription is created.
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a subscription.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
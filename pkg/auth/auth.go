@@ -1,101 +1,251 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"product-like/models"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// TokenIssuer is the "iss" claim set on every access token.
+	TokenIssuer = "product-like-issuer"
+	// AccessTokenTTL is how long an access token stays valid.
+	AccessTokenTTL = 1 * time.Hour
+)
+
+// Claims are the claims carried by access tokens. The role is informational
+// for other services; Require checks the role of the freshly loaded user.
+type Claims struct {
+	Role string `json:"role,omitempty"`
+	jwt.StandardClaims
+}
+
 // middleware for authentication and authorization
 type AuthMiddleware struct {
-	jwtSecret string
+	keys     *KeySet
+	users    UserLoader
+	cacheTTL time.Duration
+	cache    *cachedLoader
+}
+
+// Option configures an AuthMiddleware.
+type Option func(*AuthMiddleware)
+
+// WithUserLoader sets how the "sub" claim is resolved to a user. It is required.
+func WithUserLoader(loader UserLoader) Option {
+	return func(m *AuthMiddleware) {
+		m.users = loader
+	}
+}
+
+// WithKeySet signs and verifies tokens with the given keys instead of the
+// shared secret passed to NewMiddleware.
+func WithKeySet(keys *KeySet) Option {
+	return func(m *AuthMiddleware) {
+		m.keys = keys
+	}
 }
 
-// creates a new instance of AuthMiddleware
-func NewMiddleware(jwtSecret string) (*AuthMiddleware, error) {
-	return &AuthMiddleware{
-		jwtSecret: jwtSecret,
-	}, nil
+// WithUserCache keeps loaded users in memory for ttl, so a database hit per
+// request is not needed. Status changes take up to ttl to be enforced.
+func WithUserCache(ttl time.Duration) Option {
+	return func(m *AuthMiddleware) {
+		m.cacheTTL = ttl
+	}
+}
+
+// creates a new instance of AuthMiddleware. Tokens are signed with HS256 using
+// jwtSecret unless WithKeySet is given.
+func NewMiddleware(jwtSecret string, opts ...Option) (*AuthMiddleware, error) {
+	m := &AuthMiddleware{}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.keys == nil {
+		if jwtSecret == "" {
+			return nil, errors.New("auth: a JWT secret or key set is required")
+		}
+		keys, err := NewKeySet(NewHMACKey("", []byte(jwtSecret)))
+		if err != nil {
+			return nil, err
+		}
+		m.keys = keys
+	}
+
+	if m.users == nil {
+		return nil, errors.New("auth: a user loader is required")
+	}
+	if m.cacheTTL > 0 {
+		m.cache = newCachedLoader(m.users, m.cacheTTL)
+		m.users = m.cache
+	}
+
+	return m, nil
+}
+
+// InvalidateUser drops any cached copy of the user, so the next request
+// reloads it. Call it after changing a user's status, role or shops.
+func (m *AuthMiddleware) InvalidateUser(userID int64) {
+	if m.cache != nil {
+		m.cache.invalidate(userID)
+	}
+}
+
+// GenerateToken issues a signed access token for the given user. Tokens minted
+// here are the ones Authorize accepts.
+func (m *AuthMiddleware) GenerateToken(user *models.User) (string, error) {
+	claims := Claims{
+		Role: user.Role,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(AccessTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    TokenIssuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+		},
+	}
+
+	key := m.keys.signingKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signKey)
 }
 
 // function for authorization
 func (m *AuthMiddleware) Authorize() gin.HandlerFunc {
 	return func(c *gin.Context) {
-
-		// Get the JWT token from the request headers
-		tokenString := c.GetHeader("Authorization")
-		// Remove the "Bearer " prefix if it exists
-		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-		fmt.Print("tokenString: ", tokenString)
-		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing JWT token"})
+		user, status, message := m.authenticate(c)
+		if user == nil {
+			c.JSON(status, gin.H{"error": message})
 			c.Abort()
 			return
 		}
 
-		// Parse and validate the JWT token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Check the signing method and set the secret key
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("Invalid token signing method")
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		// Set the user context with the extracted user ID and the loaded user
+		c.Set("user_id", uint64(user.ID))
+		c.Set("user", user)
 
-		if err != nil {
-			fmt.Printf("\n JWT Parse Error: %s\n", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid JWT token"})
-			c.Abort()
-			return
+		// Continue with the request
+		c.Next()
+	}
+}
+
+// OptionalAuthorize sets the user like Authorize when the request carries a
+// valid token, and otherwise lets the request through anonymously. Handlers
+// behind it must cope with GetUserFromContext returning nil.
+func (m *AuthMiddleware) OptionalAuthorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, _, _ := m.authenticate(c); user != nil {
+			c.Set("user_id", uint64(user.ID))
+			c.Set("user", user)
 		}
+		c.Next()
+	}
+}
 
-		fmt.Print("\n token: ", token)
-		// Check if the token is valid and not expired
-		if !token.Valid {
-			fmt.Printf("\n JWT Token Invalid!")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid JWT token"})
-			c.Abort()
-			return
+// authenticate resolves the user from the request's JWT token. If there is no
+// usable token it returns a nil user with the status and message to respond with.
+func (m *AuthMiddleware) authenticate(c *gin.Context) (*models.User, int, string) {
+	// Get the JWT token from the request headers
+	tokenString := c.GetHeader("Authorization")
+	// Remove the "Bearer " prefix if it exists
+	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+	if tokenString == "" {
+		return nil, http.StatusUnauthorized, "Missing JWT token"
+	}
+
+	// Parse and validate the JWT token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Pick the key named by the "kid" header; tokens without one are
+		// checked against the unnamed key, if any
+		kid, _ := token.Header["kid"].(string)
+		key, err := m.keys.verificationKey(kid)
+		if err != nil {
+			return nil, err
 		}
 
-		// Extract claims from the token
-		claims, ok := token.Claims.(jwt.MapClaims)
-		fmt.Println("\n Claims: ", claims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid JWT claims"})
-			c.Abort()
-			return
+		// The algorithm must match the key, otherwise e.g. a public RSA key
+		// could be abused as an HMAC secret
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("Invalid token signing method")
 		}
+		return key.verifyKey, nil
+	})
 
-		// Extract user ID from claims as a string
-		userID, ok := claims["sub"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in JWT claims"})
-			c.Abort()
-			return
+	if err != nil {
+		return nil, http.StatusUnauthorized, "Invalid JWT token"
+	}
+
+	// Check if the token is valid and not expired
+	if !token.Valid {
+		return nil, http.StatusUnauthorized, "Invalid JWT token"
+	}
+
+	// Extract claims from the token
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, http.StatusUnauthorized, "Invalid JWT claims"
+	}
+
+	// Extract user ID from claims as a string
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return nil, http.StatusUnauthorized, "Invalid user ID in JWT claims"
+	}
+
+	// Parse the user ID as needed (e.g., convert it to an integer)
+	parsedUserID, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "Invalid user ID format in JWT claims"
+	}
+
+	// Resolve the user and make sure they may still use the API
+	user, err := m.users.LoadUser(c.Request.Context(), parsedUserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, http.StatusUnauthorized, "User not found"
 		}
+		log.Println("Error loading user:", err)
+		return nil, http.StatusInternalServerError, "Internal Server Error"
+	}
 
-		// Parse the user ID as needed (e.g., convert it to an integer)
-		parsedUserID, err := strconv.Atoi(userID)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID format in JWT claims"})
+	if !user.IsActive() {
+		return nil, http.StatusUnauthorized, "User account is not active"
+	}
+
+	return user, http.StatusOK, ""
+}
+
+// Require only lets through users with one of the given roles. It must be
+// chained after Authorize.
+func (m *AuthMiddleware) Require(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := GetUserFromContext(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
 			return
 		}
 
-		// Convert the parsed user ID to uint64
-		userUint64 := uint64(parsedUserID)
-
-		// Set the user context with the extracted user ID
-		c.Set("user_id", userUint64)
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
 
-		// Continue with the request
-		c.Next()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		c.Abort()
 	}
 }
 
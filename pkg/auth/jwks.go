@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// ECDSA keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS publishes the public keys other services need to verify our access
+// tokens. Shared HMAC keys are never included.
+func (m *AuthMiddleware) JWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := []JWK{}
+		for _, key := range m.keys.publicKeys() {
+			keys = append(keys, toJWK(key))
+		}
+
+		// Verifiers cache the set; keep it short so rotations propagate quickly
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, gin.H{"keys": keys})
+	}
+}
+
+func toJWK(key *SigningKey) JWK {
+	jwk := JWK{
+		Use: "sig",
+		Kid: key.ID,
+		Alg: key.Method.Alg(),
+	}
+
+	switch pub := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBigInt(pub.N, 0)
+		jwk.E = encodeBigInt(big.NewInt(int64(pub.E)), 0)
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeBigInt(pub.X, size)
+		jwk.Y = encodeBigInt(pub.Y, size)
+	}
+	return jwk
+}
+
+// encodeBigInt base64url-encodes n, left-padding it with zeros to size bytes.
+func encodeBigInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey is a key used to sign and/or verify access tokens. Its ID is
+// sent as the "kid" header so verifiers can pick the right key.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// signKey is nil for keys that can only verify.
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey creates a shared-secret HS256 key. HMAC keys are never published
+// in the JWKS, since their verification key is the secret itself.
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        kid,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// LoadPrivateKeyPEM reads an RSA (RS256) or ECDSA (ES256/ES384/ES512) private
+// key from a PEM file.
+func LoadPrivateKeyPEM(kid, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, signKey: rsaKey, verifyKey: &rsaKey.PublicKey}, nil
+	}
+
+	ecKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %s is neither an RSA nor an ECDSA private key", path)
+	}
+	method, err := ecdsaMethod(ecKey.Curve)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: kid, Method: method, signKey: ecKey, verifyKey: &ecKey.PublicKey}, nil
+}
+
+// LoadPublicKeyPEM reads an RSA or ECDSA public key from a PEM file. The
+// resulting key can verify tokens but not sign them.
+func LoadPublicKeyPEM(kid, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: rsaKey}, nil
+	}
+
+	ecKey, err := jwt.ParseECPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %s is neither an RSA nor an ECDSA public key", path)
+	}
+	method, err := ecdsaMethod(ecKey.Curve)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: kid, Method: method, verifyKey: ecKey}, nil
+}
+
+func ecdsaMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	case elliptic.P521():
+		return jwt.SigningMethodES512, nil
+	}
+	return nil, fmt.Errorf("auth: unsupported ECDSA curve %s", curve.Params().Name)
+}
+
+// KeySet holds the keys the middleware signs and verifies tokens with.
+//
+// Exactly one key is active and used for signing. Any other key in the set is
+// accepted for verification. Retired keys are still accepted until their
+// retirement deadline, which lets tokens signed before a rotation expire naturally.
+type KeySet struct {
+	mu        sync.RWMutex
+	active    string
+	keys      map[string]*SigningKey
+	retiredAt map[string]time.Time
+}
+
+// NewKeySet creates a KeySet that signs with active.
+func NewKeySet(active *SigningKey) (*KeySet, error) {
+	ks := &KeySet{
+		keys:      make(map[string]*SigningKey),
+		retiredAt: make(map[string]time.Time),
+	}
+	ks.Add(active)
+	if err := ks.SetActive(active.ID); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Add adds a key to the set, replacing any key with the same ID.
+func (ks *KeySet) Add(key *SigningKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[key.ID] = key
+	delete(ks.retiredAt, key.ID)
+}
+
+// SetActive makes the key with the given ID the one new tokens are signed with.
+func (ks *KeySet) SetActive(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("auth: unknown key %q", kid)
+	}
+	if key.signKey == nil {
+		return fmt.Errorf("auth: key %q cannot sign", kid)
+	}
+	ks.active = kid
+	delete(ks.retiredAt, kid)
+	return nil
+}
+
+// Retire stops accepting the key after until. The active key cannot be retired.
+func (ks *KeySet) Retire(kid string, until time.Time) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, ok := ks.keys[kid]; !ok {
+		return fmt.Errorf("auth: unknown key %q", kid)
+	}
+	if kid == ks.active {
+		return fmt.Errorf("auth: cannot retire the active key %q", kid)
+	}
+	ks.retiredAt[kid] = until
+	return nil
+}
+
+// signingKey returns the active key.
+func (ks *KeySet) signingKey() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.keys[ks.active]
+}
+
+// verificationKey returns the key with the given ID if it is still accepted.
+func (ks *KeySet) verificationKey(kid string) (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if deadline, retired := ks.retiredAt[kid]; retired && time.Now().After(deadline) {
+		return nil, fmt.Errorf("signing key %q has been retired", kid)
+	}
+	return key, nil
+}
+
+// publicKeys returns the asymmetric keys that are still accepted, ordered by ID.
+func (ks *KeySet) publicKeys() []*SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	var keys []*SigningKey
+	for kid, key := range ks.keys {
+		if deadline, retired := ks.retiredAt[kid]; retired && now.After(deadline) {
+			continue
+		}
+		switch key.verifyKey.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// ParseKeySpec parses a comma separated list of kid=path entries, as used by
+// the JWT_SIGNING_KEYS and JWT_RETIRED_KEYS settings, and loads each file.
+func ParseKeySpec(spec string) ([]*SigningKey, error) {
+	var keys []*SigningKey
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("auth: invalid key entry %q, expected kid=path", entry)
+		}
+
+		key, err := LoadPrivateKeyPEM(kid, path)
+		if err != nil {
+			// A retired or external key may only be available as a public key
+			if key, err = LoadPublicKeyPEM(kid, path); err != nil {
+				return nil, fmt.Errorf("auth: loading key %q: %w", kid, err)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"product-like/models"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+func newTestKeySet(t *testing.T) (*KeySet, *rsa.PrivateKey) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := NewKeySet(&SigningKey{ID: "rsa", Method: jwt.SigningMethodRS256, signKey: rsaKey, verifyKey: &rsaKey.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Add(&SigningKey{ID: "ec", Method: jwt.SigningMethodES256, verifyKey: &ecKey.PublicKey})
+	ks.Add(NewHMACKey("grace", []byte("grace-secret")))
+	ks.Add(NewHMACKey("expired", []byte("expired-secret")))
+	if err := ks.Retire("grace", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Retire("expired", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	return ks, rsaKey
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, jwt.StandardClaims{
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		Subject:   "7",
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAuthenticateKeySet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ks, rsaKey := newTestKeySet(t)
+	m, err := NewMiddleware("", WithKeySet(ks), WithUserLoader(UserLoaderFunc(
+		func(ctx context.Context, userID int64) (*models.User, error) {
+			return &models.User{ID: userID, Role: models.RoleShopper, Status: models.UserStatusActive}, nil
+		},
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"active key", signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey), true},
+		{"retired key within deadline", signTestToken(t, jwt.SigningMethodHS256, "grace", []byte("grace-secret")), true},
+		{"retired key past deadline", signTestToken(t, jwt.SigningMethodHS256, "expired", []byte("expired-secret")), false},
+		{"unknown kid", signTestToken(t, jwt.SigningMethodHS256, "unknown", []byte("grace-secret")), false},
+		{"missing kid", signTestToken(t, jwt.SigningMethodRS256, "", rsaKey), false},
+		{"other key under active kid", signTestToken(t, jwt.SigningMethodRS256, "rsa", otherKey), false},
+		// The public key must not be accepted as an HMAC secret
+		{"HMAC with public key", signTestToken(t, jwt.SigningMethodHS256, "rsa", publicPEM), false},
+		{"RS512 under RS256 key", signTestToken(t, jwt.SigningMethodRS512, "rsa", rsaKey), false},
+		{"RSA under ECDSA key", signTestToken(t, jwt.SigningMethodRS256, "ec", rsaKey), false},
+		{"tampered", signTestToken(t, jwt.SigningMethodRS256, "rsa", rsaKey) + "x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			c.Request.Header.Set("Authorization", "Bearer "+tt.token)
+
+			user, status, message := m.authenticate(c)
+			if got := user != nil; got != tt.ok {
+				t.Fatalf("authenticate() ok = %v, want %v (status %d, %q)", got, tt.ok, status, message)
+			}
+			if tt.ok && user.ID != 7 {
+				t.Errorf("authenticate() user ID = %d, want 7", user.ID)
+			}
+			if !tt.ok && status != http.StatusUnauthorized {
+				t.Errorf("authenticate() status = %d, want %d", status, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	ks, _ := newTestKeySet(t)
+
+	tests := []struct {
+		name    string
+		change  func() error
+		wantErr bool
+	}{
+		{"retire active key", func() error { return ks.Retire("rsa", time.Now()) }, true},
+		{"retire unknown key", func() error { return ks.Retire("unknown", time.Now()) }, true},
+		{"activate unknown key", func() error { return ks.SetActive("unknown") }, true},
+		{"activate verification-only key", func() error { return ks.SetActive("ec") }, true},
+		{"activate retired key", func() error { return ks.SetActive("expired") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Activating a retired key takes it out of retirement
+	if _, err := ks.verificationKey("expired"); err != nil {
+		t.Errorf("verificationKey() of the reactivated key: %v", err)
+	}
+	if key := ks.signingKey(); key.ID != "expired" {
+		t.Errorf("signingKey() = %q, want %q", key.ID, "expired")
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"product-like/db"
+	"product-like/models"
+)
+
+// ErrUserNotFound is returned by a UserLoader when no user matches the ID.
+var ErrUserNotFound = errors.New("user not found")
+
+// UserLoader resolves the user ID from a token's "sub" claim to a user.
+type UserLoader interface {
+	LoadUser(ctx context.Context, userID int64) (*models.User, error)
+}
+
+// UserLoaderFunc adapts a function to the UserLoader interface.
+type UserLoaderFunc func(ctx context.Context, userID int64) (*models.User, error)
+
+// LoadUser calls f(ctx, userID).
+func (f UserLoaderFunc) LoadUser(ctx context.Context, userID int64) (*models.User, error) {
+	return f(ctx, userID)
+}
+
+// DBUserLoader loads users from the users table, along with the shops staff users belong to.
+func DBUserLoader(dbConn *sql.DB) UserLoader {
+	return UserLoaderFunc(func(ctx context.Context, userID int64) (*models.User, error) {
+		user, err := db.GetUserByID(ctx, dbConn, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if user.Role == models.RoleShopStaff {
+			if user.ShopIDs, err = db.GetUserShopIDs(ctx, dbConn, userID); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	})
+}
+
+// cachedLoader keeps loaded users in memory for a short time so that
+// authenticating a request does not always cost a database round trip.
+type cachedLoader struct {
+	next UserLoader
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[int64]cachedUser
+}
+
+type cachedUser struct {
+	user      *models.User
+	expiresAt time.Time
+}
+
+func newCachedLoader(next UserLoader, ttl time.Duration) *cachedLoader {
+	return &cachedLoader{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[int64]cachedUser),
+	}
+}
+
+func (l *cachedLoader) LoadUser(ctx context.Context, userID int64) (*models.User, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	entry, ok := l.entries[userID]
+	l.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.user, nil
+	}
+
+	user, err := l.next.LoadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.entries[userID] = cachedUser{user: user, expiresAt: now.Add(l.ttl)}
+	// Drop expired entries opportunistically so the map cannot grow without bound.
+	for id, e := range l.entries {
+		if now.After(e.expiresAt) {
+			delete(l.entries, id)
+		}
+	}
+	l.mu.Unlock()
+
+	return user, nil
+}
+
+func (l *cachedLoader) invalidate(userID int64) {
+	l.mu.Lock()
+	delete(l.entries, userID)
+	l.mu.Unlock()
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"product-like/db"
+)
+
+// RefreshTokenTTL is how long a refresh token stays valid if it is not rotated.
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is
+	// presented again. The whole token family has been revoked by then.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// RefreshTokens issues, rotates and revokes opaque refresh tokens.
+//
+// Every login starts a new token family. Rotating a token issues its successor
+// in the same family; presenting a token that was already rotated means it
+// leaked, so the whole family is revoked and the user has to log in again.
+type RefreshTokens struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewRefreshTokens creates a RefreshTokens backed by the refresh_tokens table.
+func NewRefreshTokens(dbConn *sql.DB, ttl time.Duration) *RefreshTokens {
+	return &RefreshTokens{db: dbConn, ttl: ttl}
+}
+
+// Issue starts a new token family for the user and returns its first token.
+func (r *RefreshTokens) Issue(ctx context.Context, userID int64) (string, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	return r.insert(ctx, r.db, userID, familyID, nil)
+}
+
+// Rotate exchanges a refresh token for a new one in the same family and
+// returns the user it belongs to. The user is also returned along with
+// ErrRefreshTokenReused.
+func (r *RefreshTokens) Rotate(ctx context.Context, token string) (int64, string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	current, err := db.GetRefreshTokenForUpdate(ctx, tx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	if current.ReplacedBy.Valid {
+		if _, err := db.RevokeRefreshTokenFamily(ctx, tx, current.FamilyID); err != nil {
+			return 0, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, "", err
+		}
+		return current.UserID, "", ErrRefreshTokenReused
+	}
+
+	if current.RevokedAt.Valid || time.Now().After(current.ExpiresAt) {
+		return 0, "", ErrInvalidRefreshToken
+	}
+
+	next, err := r.insert(ctx, tx, current.UserID, current.FamilyID, current)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", err
+	}
+	return current.UserID, next, nil
+}
+
+// Revoke revokes the family the refresh token belongs to, ending that login
+// session. Unknown tokens are ignored.
+func (r *RefreshTokens) Revoke(ctx context.Context, token string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := db.GetRefreshTokenForUpdate(ctx, tx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.RevokeRefreshTokenFamily(ctx, tx, current.FamilyID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insert stores a new token in the family and, if previous is set, marks it as replaced.
+func (r *RefreshTokens) insert(ctx context.Context, q db.DBTX, userID int64, familyID string, previous *db.RefreshToken) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	row := &db.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(r.ttl),
+	}
+	if err := db.InsertRefreshToken(ctx, q, row); err != nil {
+		return "", err
+	}
+
+	if previous != nil {
+		if err := db.MarkRefreshTokenReplaced(ctx, q, previous.ID, row.ID); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
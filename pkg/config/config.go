@@ -0,0 +1,41 @@
+// Package config reads settings shared by the server and the command line tools from the environment.
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultDatabaseURL = "postgres://postgres:postgrespass@db:5432/product_like_db?sslmode=disable"
+
+// Get returns the value of the environment variable key, or fallback if it is unset.
+func Get(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// DatabaseURL returns the Postgres connection string from DATABASE_URL.
+func DatabaseURL() string {
+	return Get("DATABASE_URL", defaultDatabaseURL)
+}
+
+// Duration returns the environment variable key parsed as a time.Duration,
+// such as "90s" or "1h30m", or fallback if it is unset. It returns an error
+// if the value does not parse or is not positive.
+func Duration(key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be positive", key)
+	}
+	return duration, nil
+}
@@ -0,0 +1,75 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type position struct {
+	LikedAt int64 `json:"liked_at"`
+	ID      int64 `json:"id"`
+}
+
+func TestDecode(t *testing.T) {
+	codec := NewCodec([]byte("0123456789abcdef"))
+	want := position{LikedAt: 1700000000, ID: 42}
+	cursor, err := codec.Encode("liked-products:7:newest", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, signature, _ := strings.Cut(cursor, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"liked_at":1700000000,"id":1}`))
+
+	tests := []struct {
+		name   string
+		codec  *Codec
+		scope  string
+		cursor string
+		ok     bool
+	}{
+		{"valid", codec, "liked-products:7:newest", cursor, true},
+		{"other user", codec, "liked-products:8:newest", cursor, false},
+		{"other order", codec, "liked-products:7:oldest", cursor, false},
+		{"other list", codec, "products", cursor, false},
+		{"other secret", NewCodec([]byte("fedcba9876543210")), "liked-products:7:newest", cursor, false},
+		{"tampered payload", codec, "liked-products:7:newest", forged + "." + signature, false},
+		{"tampered signature", codec, "liked-products:7:newest", encoded + "." + signature[:len(signature)-2] + "AA", false},
+		{"missing signature", codec, "liked-products:7:newest", encoded, false},
+		{"empty signature", codec, "liked-products:7:newest", encoded + ".", false},
+		{"malformed signature", codec, "liked-products:7:newest", encoded + ".!!", false},
+		{"empty", codec, "liked-products:7:newest", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got position
+			err := tt.codec.Decode(tt.scope, tt.cursor, &got)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Errorf("Decode() error = %v, want %v", err, ErrInvalidCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("Decode() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeSignedGarbage(t *testing.T) {
+	codec := NewCodec([]byte("0123456789abcdef"))
+
+	// Correctly signed, but not the JSON of a position
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(`not json`))
+	cursor := encoded + "." + base64.RawURLEncoding.EncodeToString(codec.sign("scope", encoded))
+
+	var got position
+	if err := codec.Decode("scope", cursor, &got); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Decode() error = %v, want %v", err, ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,68 @@
+// Package jsonl writes values as JSON lines, one JSON document per line. The
+// development publishers and notifiers use it to make their output easy to
+// inspect and replay.
+package jsonl
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer writes values to an io.Writer. It is safe for concurrent use.
+type Writer struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes v as a line.
+func (w *Writer) Write(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(append(line, '\n'))
+	return err
+}
+
+// File appends values to a file, which is created if needed and only kept
+// open while writing. It is safe for concurrent use.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFile returns a File appending to the file at path.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Write appends v as a line.
+func (f *File) Write(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,138 @@
+package live
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the Postgres channel updates are exchanged on between instances.
+const notifyChannel = "product_updates"
+
+// subscriberBuffer is how many updates a subscriber may fall behind before
+// further updates are dropped for it.
+const subscriberBuffer = 16
+
+// Hub hands product updates to the subscribers of the product. Without
+// Postgres it only reaches subscribers of this instance.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan Update]struct{}
+
+	// db is set once updates go through Postgres NOTIFY
+	db *sql.DB
+}
+
+// NewHub returns a Hub delivering updates within this instance.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[int64]map[chan Update]struct{})}
+}
+
+// Subscribe returns a channel receiving the updates of a product and a
+// function ending the subscription, which must be called once done.
+func (h *Hub) Subscribe(productID int64) (<-chan Update, func()) {
+	ch := make(chan Update, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[productID] == nil {
+		h.subscribers[productID] = make(map[chan Update]struct{})
+	}
+	h.subscribers[productID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[productID], ch)
+			if len(h.subscribers[productID]) == 0 {
+				delete(h.subscribers, productID)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends an update to the product's subscribers on every instance.
+// Delivery is best effort: failures are logged, not returned, since the
+// change itself already happened.
+func (h *Hub) Publish(ctx context.Context, update Update) {
+	h.mu.Lock()
+	dbConn := h.db
+	h.mu.Unlock()
+
+	if dbConn == nil {
+		h.deliver(update)
+		return
+	}
+
+	// Every instance, this one included, delivers it when the notification arrives
+	payload, err := json.Marshal(update)
+	if err == nil {
+		_, err = dbConn.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, string(payload))
+	}
+	if err != nil {
+		log.Println("Error publishing live update:", err)
+		h.deliver(update)
+	}
+}
+
+// ListenPostgres makes the hub exchange updates with other instances through
+// Postgres LISTEN/NOTIFY on a dedicated connection to databaseURL, until ctx
+// is canceled.
+func (h *Hub) ListenPostgres(ctx context.Context, dbConn *sql.DB, databaseURL string) error {
+	listener := pq.NewListener(databaseURL, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("Live update listener:", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	h.mu.Lock()
+	h.db = dbConn
+	h.mu.Unlock()
+
+	go func() {
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification := <-listener.Notify:
+				// nil after a reconnect; updates sent meanwhile are lost
+				if notification == nil {
+					continue
+				}
+				var update Update
+				if err := json.Unmarshal([]byte(notification.Extra), &update); err != nil {
+					log.Println("Error decoding live update:", err)
+					continue
+				}
+				h.deliver(update)
+			}
+		}
+	}()
+	return nil
+}
+
+// deliver hands an update to this instance's subscribers of the product.
+// Subscribers that fell behind miss it rather than block the others.
+func (h *Hub) deliver(update Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[update.ProductID] {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package live pushes product changes, such as like counts, to clients
+// watching a product. Handlers publish updates to a Hub, which hands them to
+// the subscribers of the product, optionally across server instances through
+// Postgres LISTEN/NOTIFY.
+package live
+
+import "product-like/models"
+
+// Update types.
+const (
+	UpdateLikeCount = "like_count"
+	UpdateProduct   = "product"
+	UpdateDeleted   = "deleted"
+)
+
+// Update is a change to a product that watchers are told about.
+type Update struct {
+	Type      string        `json:"type"`
+	ProductID int64         `json:"product_id"`
+	LikeCount *int64        `json:"like_count,omitempty"`
+	Product   *ProductState `json:"product,omitempty"`
+}
+
+// ProductState is the part of a product that changes while a page shows it.
+type ProductState struct {
+	Status          string  `json:"status"`
+	InStock         bool    `json:"in_stock"`
+	IsPreorder      bool    `json:"is_preorder"`
+	IsPurchasable   bool    `json:"is_purchasable"`
+	OriginPrice     int64   `json:"origin_price"`
+	DiscountedPrice int64   `json:"discounted_price"`
+	DiscountedRate  float64 `json:"discounted_rate"`
+	LikeCount       int64   `json:"like_count"`
+}
+
+// LikeCountUpdate tells that a product's like count changed.
+func LikeCountUpdate(productID, likeCount int64) Update {
+	return Update{Type: UpdateLikeCount, ProductID: productID, LikeCount: &likeCount}
+}
+
+// ProductUpdate tells that a product was updated.
+func ProductUpdate(product models.Product) Update {
+	return Update{
+		Type:      UpdateProduct,
+		ProductID: int64(product.ID),
+		Product: &ProductState{
+			Status:          product.Status,
+			InStock:         product.InStock,
+			IsPreorder:      product.IsPreorder,
+			IsPurchasable:   product.IsPurchasable,
+			OriginPrice:     product.OriginPrice,
+			DiscountedPrice: product.DiscountedPrice,
+			DiscountedRate:  product.DiscountedRate,
+			LikeCount:       product.LikeCount,
+		},
+	}
+}
+
+// DeletedUpdate tells that a product was deleted.
+func DeletedUpdate(productID int64) Update {
+	return Update{Type: UpdateDeleted, ProductID: productID}
+}
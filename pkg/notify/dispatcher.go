@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/worker"
+)
+
+// MaxAttempts is how often delivering a notification is tried before it is given up on.
+const MaxAttempts = 10
+
+// retryBackoff spaces out the attempts to deliver a notification.
+var retryBackoff = worker.Backoff{Min: 30 * time.Second, Max: time.Hour}
+
+// lease is how long claimed notifications are kept from other dispatchers
+// while a batch is being delivered.
+const lease = 5 * time.Minute
+
+// Dispatcher periodically delivers the notifications that were not delivered
+// yet through a Notifier.
+type Dispatcher struct {
+	db        *sql.DB
+	notifier  Notifier
+	interval  time.Duration
+	batchSize int
+}
+
+// NewDispatcher returns a Dispatcher that checks for new notifications every
+// interval and delivers up to batchSize of them at a time.
+func NewDispatcher(dbConn *sql.DB, notifier Notifier, interval time.Duration, batchSize int) *Dispatcher {
+	return &Dispatcher{db: dbConn, notifier: notifier, interval: interval, batchSize: batchSize}
+}
+
+// Run delivers notifications until ctx is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	worker.Poll(ctx, d.interval, d.batchSize, "dispatching notifications", d.DispatchBatch)
+}
+
+// DispatchBatch delivers one batch of due notifications and returns how many
+// it claimed. The notifications are leased rather than locked, so the
+// notifier is called outside of any transaction, and the outcome of each one
+// is recorded on its own: a notification is only marked delivered once the
+// notifier accepted it, and failing to record one outcome does not undo the
+// others. Notifications the notifier fails on are retried later with
+// exponential backoff, and given up on after MaxAttempts attempts; the rest of
+// the batch goes ahead either way.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	notifications, err := db.ClaimDueNotifications(ctx, d.db, d.batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var firstErr error
+	for _, notification := range notifications {
+		if err := d.dispatch(ctx, notification); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return len(notifications), firstErr
+}
+
+// dispatch delivers a notification and records the outcome. It only returns
+// an error if the outcome could not be recorded.
+func (d *Dispatcher) dispatch(ctx context.Context, notification models.Notification) error {
+	err := d.notifier.Notify(ctx, notification)
+	if err == nil {
+		return db.MarkNotificationDelivered(ctx, d.db, notification.ID)
+	}
+
+	attempts := notification.Attempts + 1
+	giveUp := attempts >= MaxAttempts
+	log.Printf("Error delivering notification %d (attempt %d): %v", notification.ID, attempts, err)
+	retryAt := time.Now().Add(retryBackoff.Delay(notification.Attempts))
+	return db.MarkNotificationFailed(ctx, d.db, notification.ID, err.Error(), retryAt, giveUp)
+}
@@ -0,0 +1,51 @@
+// Package notify delivers user notifications, such as price drop alerts, that
+// were recorded in the notifications table.
+package notify
+
+import (
+	"context"
+	"log"
+
+	"product-like/models"
+	"product-like/pkg/jsonl"
+)
+
+// Notifier delivers a notification to its user, e.g. by push or email.
+type Notifier interface {
+	Notify(ctx context.Context, notification models.Notification) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, notification models.Notification) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, notification models.Notification) error {
+	return f(ctx, notification)
+}
+
+// LogNotifier writes notifications to the standard logger. It is meant for
+// local development.
+type LogNotifier struct{}
+
+// Notify logs the notification.
+func (LogNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	log.Printf("Notification %d for user %d: %s on product %d: %s",
+		notification.ID, notification.UserID, notification.Type, notification.ProductID, notification.Payload)
+	return nil
+}
+
+// FileNotifier appends notifications to a file as JSON lines. It is meant for
+// local development and tests of the delivery pipeline.
+type FileNotifier struct {
+	file *jsonl.File
+}
+
+// NewFileNotifier returns a FileNotifier appending to the file at path.
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{file: jsonl.NewFile(path)}
+}
+
+// Notify appends the notification to the file.
+func (n *FileNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	return n.file.Write(notification)
+}
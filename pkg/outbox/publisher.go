@@ -0,0 +1,76 @@
+// Package outbox relays the events stored in the outbox table to downstream
+// consumers, at least once each.
+package outbox
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"product-like/models"
+	"product-like/pkg/jsonl"
+)
+
+// Publisher sends an event to downstream consumers, e.g. a message broker.
+// Publish may be called more than once for the same event.
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc func(ctx context.Context, event models.Event) error
+
+// Publish calls f.
+func (f PublisherFunc) Publish(ctx context.Context, event models.Event) error {
+	return f(ctx, event)
+}
+
+// MultiPublisher publishes every event to all of the publishers. If any of
+// them fails the event is retried for all, so each must tolerate duplicates.
+func MultiPublisher(publishers ...Publisher) Publisher {
+	return PublisherFunc(func(ctx context.Context, event models.Event) error {
+		for _, publisher := range publishers {
+			if err := publisher.Publish(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriterPublisher writes events to an io.Writer as JSON lines. It is meant
+// for local development.
+type WriterPublisher struct {
+	w *jsonl.Writer
+}
+
+// NewWriterPublisher returns a WriterPublisher writing to w.
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: jsonl.NewWriter(w)}
+}
+
+// NewStdoutPublisher returns a WriterPublisher writing to standard output.
+func NewStdoutPublisher() *WriterPublisher {
+	return NewWriterPublisher(os.Stdout)
+}
+
+// Publish writes the event.
+func (p *WriterPublisher) Publish(ctx context.Context, event models.Event) error {
+	return p.w.Write(event)
+}
+
+// FilePublisher appends events to a file as JSON lines. It is meant for local
+// development.
+type FilePublisher struct {
+	file *jsonl.File
+}
+
+// NewFilePublisher returns a FilePublisher appending to the file at path.
+func NewFilePublisher(path string) *FilePublisher {
+	return &FilePublisher{file: jsonl.NewFile(path)}
+}
+
+// Publish appends the event to the file.
+func (p *FilePublisher) Publish(ctx context.Context, event models.Event) error {
+	return p.file.Write(event)
+}
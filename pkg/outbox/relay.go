@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"product-like/db"
+	"product-like/models"
+	"product-like/pkg/worker"
+)
+
+// retryBackoff spaces out the attempts to publish an event.
+var retryBackoff = worker.Backoff{Min: time.Second, Max: 10 * time.Minute}
+
+// lease is how long claimed events are kept from other relays while a batch
+// is being published.
+const lease = 5 * time.Minute
+
+// Relay periodically publishes the pending events of the outbox.
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+// NewRelay returns a Relay that checks the outbox every interval and
+// publishes up to batchSize events at a time.
+func NewRelay(dbConn *sql.DB, publisher Publisher, interval time.Duration, batchSize int) *Relay {
+	return &Relay{db: dbConn, publisher: publisher, interval: interval, batchSize: batchSize}
+}
+
+// Run publishes events until ctx is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	worker.Poll(ctx, r.interval, r.batchSize, "relaying outbox events", r.RelayBatch)
+}
+
+// RelayBatch publishes one batch of due events and returns how many it
+// claimed. The events are leased rather than locked, so the publisher is
+// called outside of any transaction, and the outcome of each event is
+// recorded on its own: failing to record one does not undo the others, so at
+// worst that one event is published again. Events that fail to publish are
+// retried later with exponential backoff; an event is only marked published
+// once the publisher accepted it.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	events, err := db.ClaimDueEvents(ctx, r.db, r.batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var firstErr error
+	for _, event := range events {
+		if err := r.relay(ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return len(events), firstErr
+}
+
+// relay publishes an event and records the outcome. It only returns an error
+// if the outcome could not be recorded.
+func (r *Relay) relay(ctx context.Context, event models.Event) error {
+	err := r.publisher.Publish(ctx, event)
+	if err == nil {
+		return db.MarkEventPublished(ctx, r.db, event.ID)
+	}
+
+	log.Printf("Error publishing event %d (attempt %d): %v", event.ID, event.Attempts+1, err)
+	retryAt := time.Now().Add(retryBackoff.Delay(event.Attempts))
+	return db.MarkEventFailed(ctx, r.db, event.ID, retryAt, err.Error())
+}
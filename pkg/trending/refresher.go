@@ -0,0 +1,50 @@
+// Package trending keeps the trending products ranking up to date by
+// periodically rebuilding it from recent likes.
+package trending
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"product-like/db"
+)
+
+// Refresher periodically rebuilds the trending products ranking.
+type Refresher struct {
+	db       *sql.DB
+	window   time.Duration
+	halfLife time.Duration
+	interval time.Duration
+}
+
+// NewRefresher returns a Refresher that ranks products by their likes of the
+// last window, with the weight of a like halving every halfLife, and rebuilds
+// the ranking every interval.
+func NewRefresher(dbConn *sql.DB, window, halfLife, interval time.Duration) *Refresher {
+	return &Refresher{db: dbConn, window: window, halfLife: halfLife, interval: interval}
+}
+
+// Run rebuilds the ranking right away and then every interval until ctx is canceled.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Refresh(ctx); err != nil {
+			log.Println("Error refreshing trending products:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh rebuilds the ranking once and returns the number of ranked products.
+func (r *Refresher) Refresh(ctx context.Context) (int64, error) {
+	return db.RefreshTrendingRankings(ctx, r.db, r.window, r.halfLife)
+}
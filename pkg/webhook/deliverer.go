@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"product-like/db"
+	"product-like/pkg/worker"
+)
+
+// MaxAttempts is how often a delivery is tried before it is dead-lettered.
+const MaxAttempts = 10
+
+// retryBackoff spaces out the attempts to send a delivery.
+var retryBackoff = worker.Backoff{Min: 10 * time.Second, Max: 6 * time.Hour}
+
+// deliveryStore is where the Deliverer claims deliveries and records how
+// they went. dbStore keeps them in the database.
+type deliveryStore interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]db.WebhookDeliveryTarget, error)
+	MarkDelivered(ctx context.Context, deliveryID int64, statusCode int) error
+	MarkFailed(ctx context.Context, deliveryID int64, statusCode int, reason string, retryAt time.Time, dead bool) error
+}
+
+type dbStore struct {
+	db *sql.DB
+}
+
+func (s dbStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]db.WebhookDeliveryTarget, error) {
+	return db.ClaimDueWebhookDeliveries(ctx, s.db, limit, lease)
+}
+
+func (s dbStore) MarkDelivered(ctx context.Context, deliveryID int64, statusCode int) error {
+	return db.MarkWebhookDelivered(ctx, s.db, deliveryID, statusCode)
+}
+
+func (s dbStore) MarkFailed(ctx context.Context, deliveryID int64, statusCode int, reason string, retryAt time.Time, dead bool) error {
+	return db.MarkWebhookFailed(ctx, s.db, deliveryID, statusCode, reason, retryAt, dead)
+}
+
+// Deliverer periodically sends the due webhook deliveries.
+type Deliverer struct {
+	store     deliveryStore
+	client    *http.Client
+	interval  time.Duration
+	batchSize int
+	lease     time.Duration
+}
+
+// NewDeliverer returns a Deliverer that checks for due deliveries every
+// interval and sends up to batchSize of them at a time with client.
+func NewDeliverer(dbConn *sql.DB, client *http.Client, interval time.Duration, batchSize int) *Deliverer {
+	return &Deliverer{
+		store:     dbStore{db: dbConn},
+		client:    client,
+		interval:  interval,
+		batchSize: batchSize,
+		lease:     leaseFor(client),
+	}
+}
+
+// leaseFor returns how long claimed deliveries are kept from other workers:
+// long enough for client to give up on a receiver, with some slack.
+func leaseFor(client *http.Client) time.Duration {
+	const slack = time.Minute
+	if client.Timeout <= 0 {
+		return 5 * slack
+	}
+	return client.Timeout + slack
+}
+
+// Run sends deliveries until ctx is canceled.
+func (d *Deliverer) Run(ctx context.Context) {
+	worker.Poll(ctx, d.interval, d.batchSize, "delivering webhooks", d.DeliverBatch)
+}
+
+// DeliverBatch sends one batch of due deliveries and returns how many it
+// claimed. The deliveries are leased rather than locked and sent
+// concurrently, outside of any transaction, so a slow receiver neither holds
+// a database connection nor delays the rest of the batch. A delivery succeeds
+// when the receiver answers with a 2xx status. Failed deliveries are retried
+// with exponential backoff, and dead-lettered after MaxAttempts attempts.
+func (d *Deliverer) DeliverBatch(ctx context.Context) (int, error) {
+	targets, err := d.store.ClaimDue(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target db.WebhookDeliveryTarget) {
+			defer wg.Done()
+			if err := d.deliver(ctx, target); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	return len(targets), firstErr
+}
+
+// deliver sends a delivery and records the outcome. It only returns an error
+// if the outcome could not be recorded.
+func (d *Deliverer) deliver(ctx context.Context, target db.WebhookDeliveryTarget) error {
+	statusCode, err := d.send(ctx, target)
+	if err == nil {
+		return d.store.MarkDelivered(ctx, target.ID, statusCode)
+	}
+
+	attempts := target.Attempts + 1
+	dead := attempts >= MaxAttempts
+	if dead {
+		log.Printf("Webhook delivery %d dead after %d attempts: %v", target.ID, attempts, err)
+	}
+	retryAt := time.Now().Add(retryBackoff.Delay(target.Attempts))
+	return d.store.MarkFailed(ctx, target.ID, statusCode, err.Error(), retryAt, dead)
+}
+
+// send posts a delivery to its subscription's URL and returns the response
+// status, or 0 if the receiver could not be reached.
+func (d *Deliverer) send(ctx context.Context, target db.WebhookDeliveryTarget) (int, error) {
+	body := []byte(target.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, target.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(target.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(target.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"product-like/db"
+	"product-like/models"
+)
+
+const testSecret = "0123456789abcdef"
+
+// fakeStore hands out the queued targets once and records the outcomes.
+type fakeStore struct {
+	mu        sync.Mutex
+	targets   []db.WebhookDeliveryTarget
+	delivered map[int64]int
+	failed    map[int64]failure
+}
+
+type failure struct {
+	statusCode int
+	reason     string
+	retryAt    time.Time
+	dead       bool
+}
+
+func newFakeStore(targets ...db.WebhookDeliveryTarget) *fakeStore {
+	return &fakeStore{targets: targets, delivered: map[int64]int{}, failed: map[int64]failure{}}
+}
+
+func (s *fakeStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]db.WebhookDeliveryTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.targets) < limit {
+		limit = len(s.targets)
+	}
+	claimed := s.targets[:limit]
+	s.targets = s.targets[limit:]
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkDelivered(ctx context.Context, deliveryID int64, statusCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered[deliveryID] = statusCode
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(ctx context.Context, deliveryID int64, statusCode int, reason string, retryAt time.Time, dead bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed[deliveryID] = failure{statusCode: statusCode, reason: reason, retryAt: retryAt, dead: dead}
+	return nil
+}
+
+// receivedRequest is what the receiver saw of a delivery.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a receiver answering every request with status and
+// sending what it received on the returned channel.
+func newReceiver(t *testing.T, status int) (*httptest.Server, <-chan receivedRequest) {
+	t.Helper()
+	received := make(chan receivedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+		received <- receivedRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func newTarget(t *testing.T, id int64, url string, attempts int) db.WebhookDeliveryTarget {
+	t.Helper()
+	payload, err := json.Marshal(Payload{EventID: id, Type: models.EventProductLiked, ProductID: 7, LikeCount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db.WebhookDeliveryTarget{
+		WebhookDelivery: models.WebhookDelivery{
+			ID:        id,
+			EventID:   id,
+			EventType: models.EventProductLiked,
+			Payload:   payload,
+			Status:    models.WebhookDeliveryPending,
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: testSecret,
+	}
+}
+
+func newTestDeliverer(store deliveryStore, client *http.Client) *Deliverer {
+	return &Deliverer{store: store, client: client, interval: time.Second, batchSize: 10, lease: time.Minute}
+}
+
+func TestDeliverBatchSignsRequests(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+	target := newTarget(t, 1, server.URL, 0)
+	deliverer := newTestDeliverer(newFakeStore(target), server.Client())
+
+	if _, err := deliverer.DeliverBatch(context.Background()); err != nil {
+		t.Fatalf("DeliverBatch() error = %v", err)
+	}
+
+	request := <-received
+	if got := string(request.body); got != string(target.Payload) {
+		t.Errorf("body = %s, want %s", got, target.Payload)
+	}
+	if got := request.header.Get(HeaderEvent); got != models.EventProductLiked {
+		t.Errorf("%s = %q, want %q", HeaderEvent, got, models.EventProductLiked)
+	}
+	if got := request.header.Get(HeaderDelivery); got != "1" {
+		t.Errorf("%s = %q, want %q", HeaderDelivery, got, "1")
+	}
+	timestamp, err := strconv.ParseInt(request.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", HeaderTimestamp, err)
+	}
+	if !Verify(testSecret, timestamp, request.body, request.header.Get(HeaderSignature)) {
+		t.Errorf("%s %q does not verify", HeaderSignature, request.header.Get(HeaderSignature))
+	}
+}
+
+func TestDeliverBatchMarksAcceptedDeliveriesDelivered(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusAccepted, http.StatusNoContent} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			server, _ := newReceiver(t, status)
+			store := newFakeStore(newTarget(t, 1, server.URL, 0), newTarget(t, 2, server.URL, 3))
+			deliverer := newTestDeliverer(store, server.Client())
+
+			claimed, err := deliverer.DeliverBatch(context.Background())
+			if err != nil {
+				t.Fatalf("DeliverBatch() error = %v", err)
+			}
+			if claimed != 2 {
+				t.Errorf("claimed = %d, want 2", claimed)
+			}
+			for _, id := range []int64{1, 2} {
+				if got, ok := store.delivered[id]; !ok || got != status {
+					t.Errorf("delivery %d: delivered status = %d (marked %v), want %d", id, got, ok, status)
+				}
+			}
+			if len(store.failed) != 0 {
+				t.Errorf("failed = %v, want none", store.failed)
+			}
+		})
+	}
+}
+
+func TestDeliverBatchRetriesFailedDeliveries(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusInternalServerError)
+	const attempts = 3
+	store := newFakeStore(newTarget(t, 1, server.URL, attempts))
+	deliverer := newTestDeliverer(store, server.Client())
+
+	before := time.Now()
+	if _, err := deliverer.DeliverBatch(context.Background()); err != nil {
+		t.Fatalf("DeliverBatch() error = %v", err)
+	}
+	after := time.Now()
+
+	failed, ok := store.failed[1]
+	if !ok {
+		t.Fatalf("delivery was not marked failed, delivered = %v", store.delivered)
+	}
+	if failed.statusCode != http.StatusInternalServerError {
+		t.Errorf("status code = %d, want %d", failed.statusCode, http.StatusInternalServerError)
+	}
+	if failed.dead {
+		t.Error("delivery dead after 4 attempts, want it retried")
+	}
+	delay := retryBackoff.Delay(attempts)
+	if failed.retryAt.Before(before.Add(delay)) || failed.retryAt.After(after.Add(delay)) {
+		t.Errorf("retry at %v, want %v after the attempt", failed.retryAt, delay)
+	}
+}
+
+func TestDeliverBatchRetriesUnreachableReceivers(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusOK)
+	url := server.URL
+	server.Close()
+
+	store := newFakeStore(newTarget(t, 1, url, 0))
+	deliverer := newTestDeliverer(store, &http.Client{Timeout: time.Second})
+
+	if _, err := deliverer.DeliverBatch(context.Background()); err != nil {
+		t.Fatalf("DeliverBatch() error = %v", err)
+	}
+
+	failed, ok := store.failed[1]
+	if !ok {
+		t.Fatal("delivery was not marked failed")
+	}
+	if failed.statusCode != 0 || failed.reason == "" || failed.dead {
+		t.Errorf("failure = %+v, want status 0 with a reason, retried", failed)
+	}
+}
+
+func TestDeliverBatchDeadLettersAfterMaxAttempts(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusBadGateway)
+	store := newFakeStore(newTarget(t, 1, server.URL, MaxAttempts-1))
+	deliverer := newTestDeliverer(store, server.Client())
+
+	if _, err := deliverer.DeliverBatch(context.Background()); err != nil {
+		t.Fatalf("DeliverBatch() error = %v", err)
+	}
+
+	failed, ok := store.failed[1]
+	if !ok {
+		t.Fatal("delivery was not marked failed")
+	}
+	if !failed.dead {
+		t.Errorf("delivery not dead after %d attempts", MaxAttempts)
+	}
+	if failed.statusCode != http.StatusBadGateway {
+		t.Errorf("status code = %d, want %d", failed.statusCode, http.StatusBadGateway)
+	}
+}
+
+func TestDeliverBatchDoesNotWaitForSlowReceivers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast, _ := newReceiver(t, http.StatusOK)
+
+	store := newFakeStore(newTarget(t, 1, slow.URL, 0), newTarget(t, 2, fast.URL, 0))
+	deliverer := newTestDeliverer(store, &http.Client{Timeout: 200 * time.Millisecond})
+
+	if _, err := deliverer.DeliverBatch(context.Background()); err != nil {
+		t.Fatalf("DeliverBatch() error = %v", err)
+	}
+	if _, ok := store.delivered[2]; !ok {
+		t.Error("fast delivery was not delivered")
+	}
+	if _, ok := store.failed[1]; !ok {
+		t.Error("slow delivery was not marked failed after the client timeout")
+	}
+}
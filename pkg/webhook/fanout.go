@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"product-like/db"
+	"product-like/models"
+)
+
+// Payload is the body of a webhook request. It only carries what a shop needs
+// to know about the event; in particular it does not identify the shopper.
+type Payload struct {
+	// EventID identifies the event, so receivers can drop duplicates.
+	EventID   int64     `json:"event_id"`
+	Type      string    `json:"type"`
+	ProductID int64     `json:"product_id"`
+	LikeCount int64     `json:"like_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewPayload builds the shop-facing payload of a like event.
+func NewPayload(event models.Event) (Payload, error) {
+	var like models.LikeEvent
+	if err := json.Unmarshal(event.Payload, &like); err != nil {
+		return Payload{}, err
+	}
+	return Payload{
+		EventID:   event.ID,
+		Type:      event.Type,
+		ProductID: event.ProductID,
+		LikeCount: like.LikeCount,
+		CreatedAt: event.CreatedAt,
+	}, nil
+}
+
+// Fanout queues webhook deliveries for the events relayed from the outbox.
+// It implements outbox.Publisher.
+type Fanout struct {
+	db *sql.DB
+}
+
+// NewFanout returns a Fanout queuing deliveries in dbConn.
+func NewFanout(dbConn *sql.DB) *Fanout {
+	return &Fanout{db: dbConn}
+}
+
+// Publish queues the event for the subscriptions of its product's shop.
+// Publishing the same event again does not queue it twice.
+func (f *Fanout) Publish(ctx context.Context, event models.Event) error {
+	if !isWebhookEvent(event.Type) {
+		return nil
+	}
+
+	payload, err := NewPayload(event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = db.EnqueueWebhookDeliveries(ctx, f.db, event, body)
+	return err
+}
+
+func isWebhookEvent(eventType string) bool {
+	for _, supported := range models.WebhookEventTypes {
+		if eventType == supported {
+			return true
+		}
+	}
+	return false
+}
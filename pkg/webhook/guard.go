@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// blockedNetworks are the ranges, besides loopback, private and link-local
+// addresses, that webhooks must not reach: "this network", carrier-grade NAT,
+// IETF protocol assignments, benchmarking, reserved and NAT64.
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// isPublicIP reports whether ip is an address webhooks may be sent to, i.e.
+// not a loopback, private, link-local, multicast or otherwise internal one.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL checks that rawURL can be subscribed: it must be an https URL
+// whose host resolves to public addresses only. Receivers are checked again
+// when connecting, see NewClient, since DNS answers can change.
+func ValidateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("must be a valid URL")
+	}
+	if u.Scheme != "https" {
+		return errors.New("must use https")
+	}
+	if u.User != nil {
+		return errors.New("must not contain credentials")
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("must have a host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("must not point to a private address")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return errors.New("must not point to a private address")
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return errors.New("host could not be resolved")
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return errors.New("must not point to a private address")
+		}
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client for sending webhooks that gives up after
+// timeout. It refuses to connect to addresses that are not public, whatever
+// the host name resolved to, and does not follow redirects or use proxies,
+// so receivers cannot steer requests to internal services.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("refusing to connect to non-public address %s", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,46 @@
+// Package webhook sends shops' like events to the URLs they subscribed,
+// signed so that receivers can tell the requests come from us.
+//
+// Each request carries a JSON Payload as its body and these headers:
+//
+//	X-Webhook-Event      the event type, e.g. product_liked
+//	X-Webhook-Delivery   the delivery ID, stable across retries
+//	X-Webhook-Timestamp  Unix seconds when the request was signed
+//	X-Webhook-Signature  sha256=<hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret>
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Request headers set on every delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the X-Webhook-Signature value for a body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid X-Webhook-Signature for the
+// body sent at timestamp. Receivers should also reject old timestamps.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
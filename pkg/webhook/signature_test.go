@@ -0,0 +1,57 @@
+package webhook
+
+import "testing"
+
+func TestVerify(t *testing.T) {
+	const secret = "0123456789abcdef"
+	body := []byte(`{"event_id":1,"type":"product_liked","product_id":7,"like_count":3}`)
+	const timestamp = int64(1700000000)
+	signature := Sign(secret, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"valid", secret, timestamp, body, signature, true},
+		{"tampered body", secret, timestamp, []byte(`{"event_id":1,"type":"product_liked","product_id":7,"like_count":4}`), signature, false},
+		{"tampered timestamp", secret, timestamp + 1, body, signature, false},
+		{"wrong secret", "fedcba9876543210", timestamp, body, signature, false},
+		{"missing prefix", secret, timestamp, body, signature[len(signaturePrefix):], false},
+		{"empty signature", secret, timestamp, body, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.body, tt.signature); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{}`)
+
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"deterministic", Sign("secret", 1, body), Sign("secret", 1, body), true},
+		{"depends on secret", Sign("secret", 1, body), Sign("other", 1, body), false},
+		{"depends on timestamp", Sign("secret", 1, body), Sign("secret", 2, body), false},
+		{"depends on body", Sign("secret", 1, body), Sign("secret", 1, []byte(`[]`)), false},
+		// "1" + "." + "2{}" must not sign like "12" + "." + "{}"
+		{"separates timestamp and body", Sign("secret", 1, []byte(`2{}`)), Sign("secret", 12, body), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a == tt.b) != tt.same {
+				t.Errorf("signatures %q and %q: same = %v, want %v", tt.a, tt.b, tt.a == tt.b, tt.same)
+			}
+		})
+	}
+}
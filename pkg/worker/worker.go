@@ -0,0 +1,62 @@
+// Package worker runs the background jobs that drain queues kept in the
+// database, such as the outbox relay, the notification dispatcher and the
+// webhook deliverer, and holds their shared retry policy.
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// BatchFunc processes one batch of up to the batch size of queued items and
+// returns how many it claimed.
+type BatchFunc func(ctx context.Context) (int, error)
+
+// Poll runs batch every interval until ctx is canceled. As long as full
+// batches of batchSize come back there may be more waiting, so it runs batch
+// again right away. Errors are logged prefixed with what, e.g. "relaying
+// outbox events", and retried on the next tick.
+func Poll(ctx context.Context, interval time.Duration, batchSize int, what string, batch BatchFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			claimed, err := batch(ctx)
+			if err != nil {
+				log.Printf("Error %s: %v", what, err)
+				break
+			}
+			if claimed < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Backoff is an exponential retry policy: the first retry waits Min, every
+// further one twice as long as the one before, up to Max.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Delay returns how long to wait before retrying something that failed
+// attempts times before.
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := b.Min
+	for i := 0; i < attempts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}